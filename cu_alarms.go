@@ -0,0 +1,482 @@
+// #############################################
+// CrunchyUtils - Alarm Clock
+//
+// This file contains:
+// - Alarms at wall-clock times, one-off or recurring
+//   on chosen weekdays, each with a label
+// - Ringing: the notification repeats until the alarm
+//   is acknowledged ([A]) or snoozed ([Z]), also used
+//   for tools set to repeat_until_ack
+// - Saving to alarms.json next to the config, so
+//   alarms are back on the next start
+// - The Alarm clock menu
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eiannone/keyboard"
+)
+
+// alarmConfig is the "alarms" section of the config file
+type alarmConfig struct {
+	SnoozeMinutes int `json:"snooze_minutes"`
+	RepeatSeconds int `json:"repeat_seconds"` // pause between two notifications while ringing
+	RingMinutes   int `json:"ring_minutes"`   // give up after this long without acknowledge
+}
+
+func defaultAlarmConfig() alarmConfig {
+	return alarmConfig{SnoozeMinutes: 5, RepeatSeconds: 30, RingMinutes: 10}
+}
+
+// alarmClock is one saved alarm
+type alarmClock struct {
+	ID      int      `json:"id"`
+	Label   string   `json:"label"`
+	Time    string   `json:"time"` // HH:MM
+	Days    []string `json:"days"` // "mon".."sun", empty = once
+	Enabled bool     `json:"enabled"`
+
+	next time.Time // next time it rings, zero if never
+}
+
+// alarmDays are the day names used in the file, in time.Weekday order
+var alarmDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// parseAlarmDays turns "once", "daily", "weekdays", "weekend"
+// or a list like "mon,wed,fri" into day names
+func parseAlarmDays(input string) ([]string, error) {
+	in := strings.ToLower(strings.TrimSpace(input))
+	switch in {
+	case "", "once":
+		return nil, nil
+	case "daily", "every day":
+		return alarmDays, nil
+	case "weekdays":
+		return []string{"mon", "tue", "wed", "thu", "fri"}, nil
+	case "weekend":
+		return []string{"sat", "sun"}, nil
+	}
+
+	seen := map[string]bool{}
+	for _, d := range strings.FieldsFunc(in, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if len(d) > 3 {
+			d = d[:3] // "monday" -> "mon"
+		}
+		if !containsString(alarmDays, d) {
+			return nil, fmt.Errorf("unknown day %q", d)
+		}
+		seen[d] = true
+	}
+	var days []string
+	for _, d := range alarmDays {
+		if seen[d] {
+			days = append(days, d)
+		}
+	}
+	return days, nil
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Repeat describes the days, e.g. "once" or "mon,wed"
+func (a *alarmClock) Repeat() string {
+	switch len(a.Days) {
+	case 0:
+		return "once"
+	case 7:
+		return "daily"
+	}
+	return strings.Join(a.Days, ",")
+}
+
+// nextRing returns the first time after now the alarm rings, zero if never
+func (a *alarmClock) nextRing(now time.Time) time.Time {
+	if !a.Enabled {
+		return time.Time{}
+	}
+	at, err := time.Parse("15:04", a.Time)
+	if err != nil {
+		return time.Time{}
+	}
+
+	// Today and the next 7 days cover every weekday
+	for i := 0; i <= 7; i++ {
+		t := time.Date(now.Year(), now.Month(), now.Day()+i, at.Hour(), at.Minute(), 0, 0, now.Location())
+		if !t.After(now) {
+			continue
+		}
+		if len(a.Days) == 0 || containsString(a.Days, alarmDays[t.Weekday()]) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// alarmRing is the alarm or notification that is ringing right now
+type alarmRing struct {
+	label string
+	alarm *alarmClock // nil for a notification set to repeat
+	n     notification
+	stop  chan struct{}
+}
+
+var (
+	alarmsMu sync.Mutex
+	alarms   []*alarmClock
+	ringing  *alarmRing
+)
+
+// alarmsPath is the file the alarms are saved in
+func alarmsPath() string {
+	return dataPath("alarms.json")
+}
+
+// loadAlarms reads the saved alarms, a missing file means none
+func loadAlarms() error {
+	data, err := os.ReadFile(alarmsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var list []*alarmClock
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("alarms %s: %v", alarmsPath(), err)
+	}
+	now := time.Now()
+	for _, a := range list {
+		a.next = a.nextRing(now)
+	}
+
+	alarmsMu.Lock()
+	alarms = list
+	alarmsMu.Unlock()
+	return nil
+}
+
+// saveAlarms writes all alarms, the caller holds alarmsMu
+func saveAlarms() error {
+	path := alarmsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(alarms, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// startAlarmClock loads the alarms and checks them every second.
+// Polling the wall clock also catches alarms after a suspend.
+func startAlarmClock() error {
+	err := loadAlarms()
+	go func() {
+		for now := range time.Tick(time.Second) {
+			alarmsMu.Lock()
+			for _, a := range alarms {
+				if a.next.IsZero() || now.Before(a.next) {
+					continue
+				}
+				if len(a.Days) == 0 {
+					a.Enabled = false // one-off, done
+					saveAlarms()
+				}
+				a.next = a.nextRing(now)
+				ringAlarm(a)
+			}
+			alarmsMu.Unlock()
+		}
+	}()
+	return err
+}
+
+// ringAlarm rings a due alarm. The caller holds alarmsMu.
+func ringAlarm(a *alarmClock) {
+	startRinging(&alarmRing{label: a.Label, alarm: a, n: notification{
+		Tool:    "alarm",
+		Title:   "Alarm",
+		Body:    a.Label + " ([A] stop, [Z] snooze)",
+		Urgency: urgencyCritical,
+	}})
+}
+
+// ringNotification rings n like an alarm, for tools set to repeat
+func ringNotification(n notification) {
+	alarmsMu.Lock()
+	defer alarmsMu.Unlock()
+	startRinging(&alarmRing{label: n.Body, n: n})
+}
+
+// startRinging notifies repeatedly until acknowledged, snoozed or the
+// ring time is over. The caller holds alarmsMu.
+func startRinging(r *alarmRing) {
+	if ringing != nil {
+		close(ringing.stop) // the newer alarm takes over
+	}
+	r.stop = make(chan struct{})
+	ringing = r
+	signalTimersChanged()
+
+	ac := config.Alarms
+	go func() {
+		repeat := time.NewTicker(time.Duration(max(ac.RepeatSeconds, 5)) * time.Second)
+		defer repeat.Stop()
+		giveUp := time.After(time.Duration(max(ac.RingMinutes, 1)) * time.Minute)
+		for {
+			go sendNotification(r.n)
+			select {
+			case <-r.stop:
+				return
+			case <-giveUp:
+				alarmsMu.Lock()
+				if ringing == r {
+					ringing = nil
+					signalTimersChanged()
+				}
+				alarmsMu.Unlock()
+				return
+			case <-repeat.C:
+			}
+		}
+	}()
+}
+
+// ackAlarm stops the ringing alarm, false if none rings
+func ackAlarm() bool {
+	alarmsMu.Lock()
+	defer alarmsMu.Unlock()
+
+	if ringing == nil {
+		return false
+	}
+	close(ringing.stop)
+	ringing = nil
+	signalTimersChanged()
+	return true
+}
+
+// snoozeAlarm stops the ringing alarm and rings it again later
+func snoozeAlarm() (time.Time, bool) {
+	alarmsMu.Lock()
+	defer alarmsMu.Unlock()
+
+	if ringing == nil {
+		return time.Time{}, false
+	}
+	r := ringing
+	close(r.stop)
+	ringing = nil
+
+	// Snooze wins over a later regular ring
+	snooze := time.Duration(max(config.Alarms.SnoozeMinutes, 1)) * time.Minute
+	again := time.Now().Add(snooze)
+	if a := r.alarm; a == nil {
+		time.AfterFunc(snooze, func() { ringNotification(r.n) })
+	} else if a.next.IsZero() || again.Before(a.next) {
+		a.next = again
+	}
+	signalTimersChanged()
+	return again, true
+}
+
+// ringingAlarm returns the label of the ringing alarm, "" if none
+func ringingAlarm() string {
+	alarmsMu.Lock()
+	defer alarmsMu.Unlock()
+	if ringing == nil {
+		return ""
+	}
+	return ringing.label
+}
+
+// nextAlarm returns label and time of the alarm that rings next,
+// a zero time if none
+func nextAlarm() (string, time.Time) {
+	alarmsMu.Lock()
+	defer alarmsMu.Unlock()
+
+	var label string
+	var next time.Time
+	for _, a := range alarms {
+		if !a.next.IsZero() && (next.IsZero() || a.next.Before(next)) {
+			label, next = a.Label, a.next
+		}
+	}
+	return label, next
+}
+
+// listAlarms returns copies of all alarms sorted by ID
+func listAlarms() []alarmClock {
+	alarmsMu.Lock()
+	defer alarmsMu.Unlock()
+
+	list := make([]alarmClock, 0, len(alarms))
+	for _, a := range alarms {
+		list = append(list, *a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// printAlarms lists the alarms with their IDs
+func printAlarms() {
+	list := listAlarms()
+	if len(list) == 0 {
+		fmt.Printf(" No alarms set\n")
+		return
+	}
+	for _, a := range list {
+		state := GREEN + "on " + RC
+		next := "next " + a.next.Format("Mon 15:04")
+		if !a.Enabled {
+			state, next = RED+"off"+RC, ""
+			if !a.next.IsZero() {
+				next = "snoozed until " + a.next.Format("15:04")
+			}
+		}
+		fmt.Printf(" #%-3d %s %s%-20s%s %s %-20s %s\n", a.ID, state, YELLOW, a.Label, RC, a.Time, a.Repeat(), next)
+	}
+}
+
+// alarmMenu adds, removes, toggles, stops and snoozes alarms
+func alarmMenu() {
+	for {
+		printCommandTitle("Alarm clock")
+		if label := ringingAlarm(); label != "" {
+			fmt.Printf(" %sRINGING: %s%s\n", RED, label, RC)
+		}
+		printAlarms()
+		line()
+		fmt.Printf(" [0] - %sReturn%s\n", RED, RC)
+		fmt.Printf(" [1] - %sAdd alarm%s\n", YELLOW, RC)
+		fmt.Printf(" [2] - %sRemove alarm%s\n", YELLOW, RC)
+		fmt.Printf(" [3] - %sTurn alarm on/off%s\n", YELLOW, RC)
+		fmt.Printf(" [A] - %sStop ringing alarm%s  [Z] - %sSnooze%s\n", YELLOW, RC, YELLOW, RC)
+		line()
+		fmt.Printf("Press key (0-3, A, Z)\n")
+
+		c, _, err := keyboard.GetSingleKey()
+		if err != nil {
+			continue
+		}
+
+		switch c {
+		case '0', 27: // Return or ESC
+			return
+		case '1':
+			addAlarmPrompt()
+		case '2', '3':
+			fmt.Printf("Alarm number%s", PROMPT)
+			input, _ := reader.ReadString('\n')
+			id, _ := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(input), "#"))
+			if err := changeAlarm(id, c == '2'); err != nil {
+				printError(err.Error())
+			}
+		case 'a', 'A':
+			if !ackAlarm() {
+				printInfo("No alarm is ringing")
+			}
+		case 'z', 'Z':
+			if again, ok := snoozeAlarm(); ok {
+				printInfo("Snoozed until " + again.Format("15:04"))
+			} else {
+				printInfo("No alarm is ringing")
+			}
+		default:
+			fmt.Printf("\nInvalid Option\n")
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		pause()
+	}
+}
+
+// addAlarmPrompt asks for time, days and label and saves the alarm
+func addAlarmPrompt() {
+	fmt.Printf("Time (HH:MM)%s", PROMPT)
+	input, _ := reader.ReadString('\n')
+	at, err := time.Parse("15:04", strings.TrimSpace(input))
+	if err != nil {
+		printError("Invalid time, use HH:MM")
+		return
+	}
+
+	fmt.Printf("Repeat (once, daily, weekdays, weekend or mon,wed,...)%s", PROMPT)
+	input, _ = reader.ReadString('\n')
+	days, err := parseAlarmDays(input)
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+
+	fmt.Printf("Label (optional)%s", PROMPT)
+	label, _ := reader.ReadString('\n')
+	label = strings.TrimSpace(label)
+	if label == "" {
+		label = "Alarm " + at.Format("15:04")
+	}
+
+	alarmsMu.Lock()
+	defer alarmsMu.Unlock()
+
+	id := 1
+	for _, a := range alarms {
+		id = max(id, a.ID+1)
+	}
+	a := &alarmClock{ID: id, Label: label, Time: at.Format("15:04"), Days: days, Enabled: true}
+	a.next = a.nextRing(time.Now())
+	alarms = append(alarms, a)
+	signalTimersChanged()
+
+	if err := saveAlarms(); err != nil {
+		printError(fmt.Sprintf("Could not save alarms: %v", err))
+		return
+	}
+	printSuccess(fmt.Sprintf("%s set for %s (%s)", a.Label, a.next.Format("Mon 02 Jan 15:04"), a.Repeat()))
+}
+
+// changeAlarm removes an alarm or turns it on/off and saves
+func changeAlarm(id int, remove bool) error {
+	alarmsMu.Lock()
+	defer alarmsMu.Unlock()
+
+	for i, a := range alarms {
+		if a.ID != id {
+			continue
+		}
+		if remove {
+			alarms = append(alarms[:i], alarms[i+1:]...)
+		} else {
+			a.Enabled = !a.Enabled
+			a.next = a.nextRing(time.Now())
+		}
+		signalTimersChanged()
+		return saveAlarms()
+	}
+	return fmt.Errorf("no alarm #%d", id)
+}
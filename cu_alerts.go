@@ -0,0 +1,283 @@
+// #############################################
+// CrunchyUtils - Monitor Alerts
+//
+// This file contains:
+// - Threshold alert rules (config)
+// - Rule evaluation with hysteresis & cooldown
+// - Alert log (memory + file) and its view
+//
+// Rules are checked on every system monitor tick.
+// A rule fires once its threshold is breached for
+// the configured time and only clears again once
+// the value moved back past the hysteresis band.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// alertRule describes one threshold check
+type alertRule struct {
+	Name       string  `json:"name"`
+	Metric     string  `json:"metric"`           // see alertMetrics
+	Above      bool    `json:"above"`            // true: fire above threshold, false: below
+	Threshold  float64 `json:"threshold"`        // in the unit of the metric
+	Hysteresis float64 `json:"hysteresis"`       // distance needed to clear again
+	For        int     `json:"for_seconds"`      // how long the breach must last
+	Cooldown   int     `json:"cooldown_seconds"` // min time between two firings
+	Enabled    bool    `json:"enabled"`
+}
+
+// alertConfig is the "alerts" section of the config file
+type alertConfig struct {
+	Enabled bool        `json:"enabled"`
+	Rules   []alertRule `json:"rules"`
+}
+
+// alertMetrics maps metric names to their display label and unit
+var alertMetrics = map[string]struct{ label, unit string }{
+	"cpu_percent":      {"CPU", "%"},
+	"ram_available_mb": {"Available RAM", " MB"},
+	"disk_percent":     {"Disk", "%"},
+	"process_mem_gb":   {"Process", " GB"},
+}
+
+func defaultAlertConfig() alertConfig {
+	return alertConfig{
+		Enabled: true,
+		Rules: []alertRule{
+			{Name: "High CPU", Metric: "cpu_percent", Above: true, Threshold: 90, Hysteresis: 10, For: 30, Cooldown: 300, Enabled: true},
+			{Name: "Low RAM", Metric: "ram_available_mb", Above: false, Threshold: 500, Hysteresis: 200, For: 5, Cooldown: 300, Enabled: true},
+			{Name: "Disk full", Metric: "disk_percent", Above: true, Threshold: 95, Hysteresis: 2, For: 0, Cooldown: 3600, Enabled: true},
+			{Name: "Memory hog", Metric: "process_mem_gb", Above: true, Threshold: 4, Hysteresis: 0.5, For: 10, Cooldown: 600, Enabled: true},
+		},
+	}
+}
+
+// alertEvent is one entry in the alert log
+type alertEvent struct {
+	Time    time.Time
+	Message string
+	Cleared bool // true if this entry is a recovery
+}
+
+// alertState tracks one rule for one subject (e.g. one disk)
+type alertState struct {
+	since     time.Time // first tick of the current breach
+	firing    bool
+	lastFired time.Time
+}
+
+var (
+	alertMu     sync.Mutex
+	alertStates = map[string]*alertState{}
+	alertLog    []alertEvent
+)
+
+// metricValues returns all (subject, value) pairs a metric has in snap.
+// Subject is empty for single-value metrics.
+func metricValues(metric string, snap sysSnapshot) map[string]float64 {
+	values := map[string]float64{}
+	switch metric {
+	case "cpu_percent":
+		values[""] = snap.EffectiveCPUPercent()
+	case "ram_available_mb":
+		if total, _, avail := snap.EffectiveRAM(); total > 0 {
+			values[""] = float64(avail) / 1024 / 1024
+		}
+	case "disk_percent":
+		for _, d := range snap.Disks {
+			values[d.Mount] = d.UsedPercent
+		}
+	case "process_mem_gb":
+		for _, p := range snap.Processes {
+			gb := float64(p.Memory) / 1024 / 1024 / 1024
+			// Same name can run many times, the biggest one counts
+			if gb > values[p.Name] {
+				values[p.Name] = gb
+			}
+		}
+	}
+	return values
+}
+
+// checkAlerts evaluates all enabled rules against snap
+// and fires notifyAlarm for every rule that trips
+func checkAlerts(snap sysSnapshot) {
+	if !config.Alerts.Enabled {
+		return
+	}
+
+	alertMu.Lock()
+	defer alertMu.Unlock()
+
+	for _, rule := range config.Alerts.Rules {
+		if !rule.Enabled {
+			continue
+		}
+		for _, e := range evalAlertRule(rule, metricValues(rule.Metric, snap), snap.Time) {
+			logAlert(e.Time, e.Message, e.Cleared)
+			if !e.Cleared {
+				go notifyAlarm(notification{Tool: "alert", Title: "Alert", Body: e.Message, Urgency: urgencyCritical})
+			}
+		}
+	}
+}
+
+// evalAlertRule updates the states of rule with the current values
+// and returns what fired or cleared. Subjects missing from values are
+// gone (process exited, disk unmounted), their states are dropped.
+// Caller must hold alertMu.
+func evalAlertRule(rule alertRule, values map[string]float64, now time.Time) []alertEvent {
+	var events []alertEvent
+	for subject, value := range values {
+		key := rule.Name + "|" + subject
+		st, ok := alertStates[key]
+		if !ok {
+			st = &alertState{}
+			alertStates[key] = st
+		}
+
+		breach := value < rule.Threshold
+		cleared := value > rule.Threshold+rule.Hysteresis
+		if rule.Above {
+			breach = value > rule.Threshold
+			cleared = value < rule.Threshold-rule.Hysteresis
+		}
+
+		// Firing rules stay quiet until the value leaves the hysteresis band
+		if st.firing {
+			if cleared {
+				st.firing = false
+				st.since = time.Time{}
+				events = append(events, alertEvent{Time: now, Message: alertMessage(rule, subject, value, true), Cleared: true})
+			}
+			continue
+		}
+
+		if !breach {
+			st.since = time.Time{}
+			continue
+		}
+		if st.since.IsZero() {
+			st.since = now
+		}
+
+		// Breach must last long enough and the cooldown must be over
+		if now.Sub(st.since) < time.Duration(rule.For)*time.Second {
+			continue
+		}
+		if !st.lastFired.IsZero() && now.Sub(st.lastFired) < time.Duration(rule.Cooldown)*time.Second {
+			continue
+		}
+
+		st.firing = true
+		st.lastFired = now
+		events = append(events, alertEvent{Time: now, Message: alertMessage(rule, subject, value, false)})
+	}
+
+	prefix := rule.Name + "|"
+	for key, st := range alertStates {
+		subject, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		if _, seen := values[subject]; seen {
+			continue
+		}
+		if st.firing {
+			events = append(events, alertEvent{Time: now, Message: fmt.Sprintf("%s: %s is gone", rule.Name, alertSubject(rule, subject)), Cleared: true})
+		}
+		delete(alertStates, key)
+	}
+	return events
+}
+
+// alertMessage builds a readable text like
+// "High CPU: CPU above 90% for 30s (now 97%)"
+func alertMessage(rule alertRule, subject string, value float64, recovered bool) string {
+	m := alertMetrics[rule.Metric]
+	what := alertSubject(rule, subject)
+
+	if recovered {
+		return fmt.Sprintf("%s: %s back to normal (now %.1f%s)", rule.Name, what, value, m.unit)
+	}
+
+	dir := "below"
+	if rule.Above {
+		dir = "above"
+	}
+	msg := fmt.Sprintf("%s: %s %s %g%s", rule.Name, what, dir, rule.Threshold, m.unit)
+	if rule.For > 0 {
+		msg += fmt.Sprintf(" for %ds", rule.For)
+	}
+	return msg + fmt.Sprintf(" (now %.1f%s)", value, m.unit)
+}
+
+// alertSubject names what a rule watches, e.g. "Disk /home"
+func alertSubject(rule alertRule, subject string) string {
+	what := rule.Metric
+	if m, ok := alertMetrics[rule.Metric]; ok {
+		what = m.label
+	}
+	if subject != "" {
+		what += " " + subject
+	}
+	return what
+}
+
+// logAlert stores an alert in memory and appends it to alerts.log.
+// Caller must hold alertMu.
+func logAlert(t time.Time, msg string, cleared bool) {
+	alertLog = append(alertLog, alertEvent{Time: t, Message: msg, Cleared: cleared})
+
+	state := "FIRED"
+	if cleared {
+		state = "CLEARED"
+	}
+
+	f, err := os.OpenFile(dataPath("alerts.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return // log file is best effort
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "%s [%s] %s\n", t.Format("2006-01-02 15:04:05"), state, msg)
+}
+
+// showAlertLog writes the latest alerts of this session to w
+func showAlertLog(w io.Writer) {
+	alertMu.Lock()
+	events := append([]alertEvent(nil), alertLog...)
+	alertMu.Unlock()
+
+	fprintCommandTitle(w, "Alert Log")
+	fline(w)
+
+	if len(events) == 0 {
+		fmt.Fprintf(w, "No alerts yet\n")
+	}
+
+	// Only the newest entries fit on screen
+	_, rows := termSize()
+	if n := max(rows-9, 1); len(events) > n {
+		events = events[len(events)-n:]
+	}
+	for _, e := range events {
+		color := RED
+		if e.Cleared {
+			color = GREEN
+		}
+		fmt.Fprintf(w, "%s%s%s %s\n", color, e.Time.Format("15:04:05"), RC, e.Message)
+	}
+	fline(w)
+	fmt.Fprintf(w, "Full log: %s\n", dataPath("alerts.log"))
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// alertStep feeds one value per subject at a time offset and lists
+// what must happen: "fired", "cleared" or "" for nothing
+type alertStep struct {
+	at     time.Duration
+	values map[string]float64
+	want   string
+}
+
+func runAlertSteps(t *testing.T, rule alertRule, steps []alertStep) {
+	t.Helper()
+	alertMu.Lock()
+	defer alertMu.Unlock()
+	alertStates = map[string]*alertState{}
+
+	start := time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC)
+	for i, s := range steps {
+		events := evalAlertRule(rule, s.values, start.Add(s.at))
+		got := ""
+		if len(events) > 1 {
+			t.Fatalf("step %d: got %d events, want at most one", i, len(events))
+		}
+		if len(events) == 1 {
+			got = "fired"
+			if events[0].Cleared {
+				got = "cleared"
+			}
+		}
+		if got != s.want {
+			t.Errorf("step %d (%s, %v): got %q, want %q", i, s.at, s.values, got, s.want)
+		}
+	}
+}
+
+var cpuRule = alertRule{Name: "High CPU", Metric: "cpu_percent", Above: true, Threshold: 90, Hysteresis: 10, Enabled: true}
+
+func TestAlertHysteresis(t *testing.T) {
+	runAlertSteps(t, cpuRule, []alertStep{
+		{0, map[string]float64{"": 95}, "fired"},
+		{time.Second, map[string]float64{"": 85}, ""}, // still inside the band
+		{2 * time.Second, map[string]float64{"": 91}, ""},
+		{3 * time.Second, map[string]float64{"": 79}, "cleared"},
+		{4 * time.Second, map[string]float64{"": 95}, "fired"},
+	})
+}
+
+func TestAlertForDuration(t *testing.T) {
+	rule := cpuRule
+	rule.For = 30
+	runAlertSteps(t, rule, []alertStep{
+		{0, map[string]float64{"": 95}, ""},
+		{20 * time.Second, map[string]float64{"": 95}, ""},
+		{25 * time.Second, map[string]float64{"": 50}, ""}, // breach interrupted, starts over
+		{30 * time.Second, map[string]float64{"": 95}, ""},
+		{50 * time.Second, map[string]float64{"": 95}, ""},
+		{60 * time.Second, map[string]float64{"": 95}, "fired"},
+	})
+}
+
+func TestAlertCooldown(t *testing.T) {
+	rule := cpuRule
+	rule.Cooldown = 300
+	runAlertSteps(t, rule, []alertStep{
+		{0, map[string]float64{"": 95}, "fired"},
+		{10 * time.Second, map[string]float64{"": 50}, "cleared"},
+		{20 * time.Second, map[string]float64{"": 95}, ""}, // cooling down
+		{5 * time.Minute, map[string]float64{"": 95}, "fired"},
+	})
+}
+
+func TestAlertBelow(t *testing.T) {
+	rule := alertRule{Name: "Low RAM", Metric: "ram_available_mb", Threshold: 500, Hysteresis: 200, Enabled: true}
+	runAlertSteps(t, rule, []alertStep{
+		{0, map[string]float64{"": 400}, "fired"},
+		{time.Second, map[string]float64{"": 650}, ""},
+		{2 * time.Second, map[string]float64{"": 701}, "cleared"},
+	})
+}
+
+func TestAlertSubjectGone(t *testing.T) {
+	rule := alertRule{Name: "Disk full", Metric: "disk_percent", Above: true, Threshold: 95, Enabled: true}
+	runAlertSteps(t, rule, []alertStep{
+		{0, map[string]float64{"/": 50, "/mnt/usb": 99}, "fired"},
+		{time.Second, map[string]float64{"/": 50}, "cleared"}, // unmounted while firing
+		{2 * time.Second, map[string]float64{"/": 50}, ""},
+	})
+
+	alertMu.Lock()
+	defer alertMu.Unlock()
+	if len(alertStates) != 1 {
+		t.Errorf("got %d states, want only the one for /", len(alertStates))
+	}
+}
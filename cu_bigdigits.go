@@ -0,0 +1,133 @@
+// #############################################
+// CrunchyUtils - Big Digits
+//
+// This file contains:
+// - Block digits in the crunchytext style for the
+//   full-screen countdown and stopwatch
+// - Centered frames with a progress bar
+// - The color of a countdown as its deadline nears
+//
+// [F] in the timer screens switches between the big
+// and the one-line display, "display.big_digits" in
+// the config sets the default.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// displayConfig is the "display" section of the config file
+type displayConfig struct {
+	BigDigits bool `json:"big_digits"` // timers start in the big digit view
+}
+
+func defaultDisplayConfig() displayConfig {
+	return displayConfig{BigDigits: false}
+}
+
+// bigGlyphs are 5 rows high, '#' is ink
+var bigGlyphs = map[rune][5]string{
+	'0': {"######", "##  ##", "##  ##", "##  ##", "######"},
+	'1': {"  ##  ", "####  ", "  ##  ", "  ##  ", "######"},
+	'2': {"######", "    ##", "######", "##    ", "######"},
+	'3': {"######", "    ##", " #####", "    ##", "######"},
+	'4': {"##  ##", "##  ##", "######", "    ##", "    ##"},
+	'5': {"######", "##    ", "######", "    ##", "######"},
+	'6': {"######", "##    ", "######", "##  ##", "######"},
+	'7': {"######", "    ##", "   ## ", "  ##  ", "  ##  "},
+	'8': {"######", "##  ##", "######", "##  ##", "######"},
+	'9': {"######", "##  ##", "######", "    ##", "######"},
+	':': {"  ", "##", "  ", "##", "  "},
+	'.': {"  ", "  ", "  ", "  ", "##"},
+	' ': {"  ", "  ", "  ", "  ", "  "},
+}
+
+// bigShades fill the rows like the crunchytext banner
+var bigShades = [5]string{"█", "▓", "▒", "▓", "█"}
+
+// bigText renders s in block digits, unknown characters are skipped
+func bigText(s string) []string {
+	rows := make([]string, 5)
+	first := true
+	for _, r := range s {
+		g, ok := bigGlyphs[r]
+		if !ok {
+			continue
+		}
+		for i := range rows {
+			if !first {
+				rows[i] += "  "
+			}
+			rows[i] += strings.ReplaceAll(g[i], "#", bigShades[i])
+		}
+		first = false
+	}
+	return rows
+}
+
+// progressBar draws a bar width characters wide, frac is 0 to 1
+func progressBar(frac float64, width int) string {
+	frac = min(max(frac, 0), 1)
+	filled := int(frac * float64(width))
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
+
+// countdownColor is green, yellow in the last fifth or minute and
+// red in the last ten seconds
+func countdownColor(left, total time.Duration) string {
+	switch {
+	case left <= 10*time.Second:
+		return RED
+	case left <= time.Minute || left*5 <= total:
+		return YELLOW
+	}
+	return GREEN
+}
+
+// bigFits reports whether the rendered text fits next to a small margin
+func bigFits(rows []string) bool {
+	cols, lines := termSize()
+	return visibleLen(rows[0])+4 <= cols && lines >= 14
+}
+
+// fprintBigFrame writes a full-screen frame: title on top, keys at the
+// bottom and body centered in between
+func fprintBigFrame(w io.Writer, title string, body []string, keys string) {
+	cols, lines := termSize()
+
+	fprintCommandTitle(w, title)
+	fline(w)
+
+	// Title and keys take two lines each with their separators
+	free := max(lines-4-len(body), 0)
+	fmt.Fprint(w, strings.Repeat("\n", free/2))
+	for _, l := range body {
+		pad := max((cols-visibleLen(l))/2, 0)
+		fmt.Fprintf(w, "%s%s\n", strings.Repeat(" ", pad), l)
+	}
+	fmt.Fprint(w, strings.Repeat("\n", free-free/2))
+
+	fline(w)
+	fmt.Fprintf(w, "%s\n", keys)
+}
+
+// bigClock builds the body of a big digit frame: the digits in color,
+// a progress bar below them if frac >= 0, then the info lines
+func bigClock(rows []string, color string, frac float64, info ...string) []string {
+	body := make([]string, 0, len(rows)+len(info)+3)
+	for _, r := range rows {
+		body = append(body, color+r+RC)
+	}
+	body = append(body, "")
+	if frac >= 0 {
+		body = append(body, color+progressBar(frac, visibleLen(rows[0])-2)+RC, "")
+	}
+	return append(body, info...)
+}
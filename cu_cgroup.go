@@ -0,0 +1,355 @@
+// #############################################
+// CrunchyUtils - Cgroup Limits
+//
+// This file contains:
+// - Detection of cgroup v1/v2 limits that apply to
+//   us (memory, CPU quota, PIDs)
+// - Cgroup of a process and the per-cgroup usage
+//   breakdown shown by the monitor
+//
+// In a container or a systemd slice the host numbers
+// (RAM, cores) are not what we can actually use. The
+// monitor shows both and computes percentages
+// against the effective limits.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cgroupInfo holds the limits and usage of our own cgroup.
+// Zero limits mean "no limit".
+type cgroupInfo struct {
+	Version     int     `json:"version"` // 0 = no cgroups (not Linux)
+	Path        string  `json:"path"`
+	MemoryLimit uint64  `json:"memory_limit_bytes"`
+	MemoryUsed  uint64  `json:"memory_used_bytes"` // without reclaimable page cache
+	CPULimit    float64 `json:"cpu_limit_cores"`
+	CPUUsage    uint64  `json:"cpu_usage_usec"` // total CPU time, for rates
+	PidsLimit   uint64  `json:"pids_limit"`
+	PidsCurrent uint64  `json:"pids_current"`
+}
+
+// cgroupMount is one cgroup filesystem from /proc/self/mountinfo
+type cgroupMount struct {
+	root   string // path inside the hierarchy that is mounted
+	point  string // where it is mounted
+	v2     bool
+	fields map[string]bool // v1 controllers like "memory", "cpu"
+}
+
+// procCgroupLine is one line of /proc/<pid>/cgroup
+type procCgroupLine struct {
+	Controllers string // "cpu,cpuacct" (v1), "" for the v2 (unified) entry
+	Path        string
+}
+
+// parseProcCgroupLines parses /proc/<pid>/cgroup in file order
+func parseProcCgroupLines(data string) []procCgroupLine {
+	var lines []procCgroupLine
+	for _, l := range strings.Split(strings.TrimSpace(data), "\n") {
+		// Lines look like "0::/user.slice/..." (v2) or "4:cpu,cpuacct:/..." (v1)
+		parts := strings.SplitN(l, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		lines = append(lines, procCgroupLine{Controllers: parts[1], Path: parts[2]})
+	}
+	return lines
+}
+
+// parseProcCgroup parses /proc/<pid>/cgroup into controller -> path.
+// The v2 (unified) entry has the key "".
+func parseProcCgroup(data string) map[string]string {
+	groups := map[string]string{}
+	for _, l := range parseProcCgroupLines(data) {
+		if l.Controllers == "" {
+			groups[""] = l.Path
+			continue
+		}
+		for _, c := range strings.Split(l.Controllers, ",") {
+			groups[c] = l.Path
+		}
+	}
+	return groups
+}
+
+// cgroupMounts lists the mounted cgroup filesystems
+func cgroupMounts() []cgroupMount {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var mounts []cgroupMount
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// "36 32 0:32 / /sys/fs/cgroup/memory rw,relatime - cgroup cgroup rw,memory"
+		pre, post, ok := strings.Cut(sc.Text(), " - ")
+		if !ok {
+			continue
+		}
+		a, b := strings.Fields(pre), strings.Fields(post)
+		if len(a) < 5 || len(b) < 3 {
+			continue
+		}
+		m := cgroupMount{root: a[3], point: a[4], fields: map[string]bool{}}
+		switch b[0] {
+		case "cgroup2":
+			m.v2 = true
+		case "cgroup":
+			for _, o := range strings.Split(b[2], ",") {
+				m.fields[o] = true
+			}
+		default:
+			continue
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts
+}
+
+// cgroupDir finds the directory of our cgroup for a controller.
+// v1 hierarchies win over v2 in hybrid setups, since there the
+// controllers are attached to v1.
+func cgroupDir(controller, file string, groups map[string]string, mounts []cgroupMount) (dir, mount string, v2 bool) {
+	join := func(m cgroupMount, path string) string {
+		// In containers the mount root is our own cgroup already
+		rel := path
+		if m.root != "/" {
+			rel = strings.TrimPrefix(path, m.root)
+		}
+		return filepath.Join(m.point, rel)
+	}
+
+	if path, ok := groups[controller]; ok {
+		for _, m := range mounts {
+			if !m.v2 && m.fields[controller] {
+				d := join(m, path)
+				if _, err := os.Stat(filepath.Join(d, file)); err == nil {
+					return d, m.point, false
+				}
+			}
+		}
+	}
+	if path, ok := groups[""]; ok {
+		for _, m := range mounts {
+			if m.v2 {
+				d := join(m, path)
+				if _, err := os.Stat(filepath.Join(d, file)); err == nil {
+					return d, m.point, true
+				}
+			}
+		}
+	}
+	return "", "", false
+}
+
+// readCgroupValue reads a single number, "max" and -1 give 0
+func readCgroupValue(path string) uint64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	v, err := strconv.ParseUint(strings.Fields(string(data) + " 0")[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// readCgroupStat reads one key of a "key value" file like memory.stat
+func readCgroupStat(path, key string) uint64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	for _, l := range strings.Split(string(data), "\n") {
+		if k, v, ok := strings.Cut(l, " "); ok && k == key {
+			n, _ := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// cgroupLimit walks from dir up to the mount point and returns the
+// smallest limit, a parent's limit applies to all children too
+func cgroupLimit(dir, mount string, read func(dir string) uint64) uint64 {
+	var limit uint64
+	for {
+		if v := read(dir); v > 0 && (limit == 0 || v < limit) {
+			limit = v
+		}
+		if dir == mount || len(dir) <= len(mount) {
+			return limit
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// cgroupCPUMax returns the smallest v2 CPU quota from dir up to
+// the mount point in cores, 0 if unlimited
+func cgroupCPUMax(dir, mount string) float64 {
+	var cores float64
+	for d := dir; ; d = filepath.Dir(d) {
+		// "max 100000" or "50000 100000"
+		f := strings.Fields(readFileString(filepath.Join(d, "cpu.max")))
+		if len(f) == 2 {
+			quota, err1 := strconv.ParseFloat(f[0], 64)
+			period, err2 := strconv.ParseFloat(f[1], 64)
+			if err1 == nil && err2 == nil && period > 0 && (cores == 0 || quota/period < cores) {
+				cores = quota / period
+			}
+		}
+		if d == mount || len(d) <= len(mount) {
+			return cores
+		}
+	}
+}
+
+// cgroupNoLimit is what v1 reports for "unlimited" memory (page aligned max int64)
+const cgroupNoLimit = 1 << 62
+
+// readCgroup returns the limits and usage of our own cgroup
+func readCgroup() cgroupInfo {
+	var info cgroupInfo
+
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return info
+	}
+	groups := parseProcCgroup(string(data))
+	mounts := cgroupMounts()
+
+	// Memory
+	if dir, mount, v2 := cgroupDir("memory", "memory.stat", groups, mounts); dir != "" {
+		info.Version, info.Path = 1, groups["memory"]
+		if v2 {
+			info.Version, info.Path = 2, groups[""]
+			info.MemoryLimit = cgroupLimit(dir, mount, func(d string) uint64 {
+				return readCgroupValue(filepath.Join(d, "memory.max"))
+			})
+			info.MemoryUsed = readCgroupValue(filepath.Join(dir, "memory.current"))
+			info.MemoryUsed -= min(info.MemoryUsed, readCgroupStat(filepath.Join(dir, "memory.stat"), "inactive_file"))
+		} else {
+			// v1 already applies the parents (hierarchical_memory_limit)
+			info.MemoryLimit = readCgroupStat(filepath.Join(dir, "memory.stat"), "hierarchical_memory_limit")
+			if info.MemoryLimit == 0 {
+				info.MemoryLimit = readCgroupValue(filepath.Join(dir, "memory.limit_in_bytes"))
+			}
+			info.MemoryUsed = readCgroupValue(filepath.Join(dir, "memory.usage_in_bytes"))
+			info.MemoryUsed -= min(info.MemoryUsed, readCgroupStat(filepath.Join(dir, "memory.stat"), "total_inactive_file"))
+		}
+		if info.MemoryLimit >= cgroupNoLimit {
+			info.MemoryLimit = 0
+		}
+	}
+
+	// CPU quota, in cores
+	if dir, mount, v2 := cgroupDir("cpu", "cpu.max", groups, mounts); v2 {
+		info.Version = max(info.Version, 2)
+		info.CPULimit = cgroupCPUMax(dir, mount)
+		info.CPUUsage = readCgroupStat(filepath.Join(dir, "cpu.stat"), "usage_usec")
+	} else if dir, _, _ := cgroupDir("cpu", "cpu.cfs_quota_us", groups, mounts); dir != "" {
+		info.Version = max(info.Version, 1)
+		quota, _ := strconv.ParseInt(strings.TrimSpace(readFileString(filepath.Join(dir, "cpu.cfs_quota_us"))), 10, 64)
+		period := readCgroupValue(filepath.Join(dir, "cpu.cfs_period_us"))
+		if quota > 0 && period > 0 {
+			info.CPULimit = float64(quota) / float64(period)
+		}
+		if acct, _, _ := cgroupDir("cpuacct", "cpuacct.usage", groups, mounts); acct != "" {
+			info.CPUUsage = readCgroupValue(filepath.Join(acct, "cpuacct.usage")) / 1000 // ns -> usec
+		}
+	}
+
+	// PIDs
+	if dir, mount, _ := cgroupDir("pids", "pids.current", groups, mounts); dir != "" {
+		info.Version = max(info.Version, 1)
+		info.PidsLimit = cgroupLimit(dir, mount, func(d string) uint64 {
+			return readCgroupValue(filepath.Join(d, "pids.max"))
+		})
+		info.PidsCurrent = readCgroupValue(filepath.Join(dir, "pids.current"))
+	}
+
+	return info
+}
+
+// readFileString returns the content of a file or ""
+func readFileString(path string) string {
+	data, _ := os.ReadFile(path)
+	return string(data)
+}
+
+// procCgroupPath returns the cgroup of pid used for grouping:
+// the unified path, or the v1 memory/systemd path on older systems
+func procCgroupPath(pid int32) string {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(int(pid)) + "/cgroup")
+	if err != nil {
+		return ""
+	}
+	groups := parseProcCgroup(string(data))
+	for _, key := range []string{"", "name=systemd", "memory"} {
+		if p := groups[key]; p != "" && p != "/" {
+			return p
+		}
+	}
+	return "/"
+}
+
+// cgroupUnit shortens a cgroup path to its systemd unit or last element,
+// e.g. "/system.slice/nginx.service" -> "nginx.service"
+func cgroupUnit(path string) string {
+	if path == "" || path == "/" {
+		return "/"
+	}
+	return filepath.Base(path)
+}
+
+// cgroupUsage is the summed usage of all processes in one cgroup
+type cgroupUsage struct {
+	Group  string  `json:"group"`
+	Procs  int     `json:"procs"`
+	CPU    float64 `json:"cpu_percent"`
+	Memory uint64  `json:"memory_bytes"`
+}
+
+// cgroupBreakdown sums process usage per cgroup, biggest memory first
+func cgroupBreakdown(procs []procInfo) []cgroupUsage {
+	byGroup := map[string]*cgroupUsage{}
+	for _, p := range procs {
+		if p.Cgroup == "" {
+			continue
+		}
+		u, ok := byGroup[p.Cgroup]
+		if !ok {
+			u = &cgroupUsage{Group: p.Cgroup}
+			byGroup[p.Cgroup] = u
+		}
+		u.Procs++
+		u.CPU += p.CPU
+		u.Memory += p.Memory
+	}
+
+	list := make([]cgroupUsage, 0, len(byGroup))
+	for _, u := range byGroup {
+		list = append(list, *u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Memory != list[j].Memory {
+			return list[i].Memory > list[j].Memory
+		}
+		return list[i].Group < list[j].Group
+	})
+	return list
+}
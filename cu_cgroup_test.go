@@ -0,0 +1,127 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseProcCgroup(t *testing.T) {
+	tests := []struct {
+		file string
+		want map[string]string
+	}{
+		{"proc-cgroup-v2.txt", map[string]string{
+			"": "/user.slice/user-1000.slice/session-2.scope",
+		}},
+		{"proc-cgroup-v1.txt", map[string]string{
+			"memory": "/docker/3f2a", "cpu": "/docker/3f2a", "cpuacct": "/docker/3f2a",
+			"pids": "/docker/3f2a", "name=systemd": "/docker/3f2a",
+		}},
+		{"proc-cgroup-hybrid.txt", map[string]string{
+			"pids":         "/user.slice/user-1000.slice/session-2.scope",
+			"memory":       "/user.slice/user-1000.slice/session-2.scope",
+			"cpu":          "/user.slice",
+			"cpuacct":      "/user.slice",
+			"name=systemd": "/user.slice/user-1000.slice/session-2.scope",
+			"":             "/user.slice/user-1000.slice/session-2.scope",
+		}},
+		{"", map[string]string{}},
+	}
+	for _, tt := range tests {
+		data := ""
+		if tt.file != "" {
+			data = readTestdata(t, tt.file)
+		}
+		if got := parseProcCgroup(data); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestParseProcCgroupLines(t *testing.T) {
+	lines := parseProcCgroupLines(readTestdata(t, "proc-cgroup-hybrid.txt") + "garbage\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5", len(lines))
+	}
+	if l := lines[2]; l.Controllers != "cpu,cpuacct" || l.Path != "/user.slice" {
+		t.Errorf("got %+v", l)
+	}
+	if l := lines[4]; l.Controllers != "" || l.Path != "/user.slice/user-1000.slice/session-2.scope" {
+		t.Errorf("got %+v", l)
+	}
+}
+
+func TestCgroupLimitsV2(t *testing.T) {
+	mounts := []cgroupMount{{root: "/", point: filepath.Join("testdata", "cgroup-v2"), v2: true}}
+	groups := map[string]string{"": "/system.slice/nginx.service"}
+
+	dir, mount, v2 := cgroupDir("memory", "memory.max", groups, mounts)
+	if want := filepath.Join("testdata", "cgroup-v2", "system.slice", "nginx.service"); dir != want || !v2 {
+		t.Fatalf("got dir %q (v2 %v), want %q", dir, v2, want)
+	}
+
+	tests := []struct {
+		file string
+		want uint64
+	}{
+		{"memory.max", 4 << 30}, // the slice is smaller than the service
+		{"pids.max", 1000},
+	}
+	for _, tt := range tests {
+		got := cgroupLimit(dir, mount, func(d string) uint64 {
+			return readCgroupValue(filepath.Join(d, tt.file))
+		})
+		if got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.file, got, tt.want)
+		}
+	}
+	if got := readCgroupValue(filepath.Join(dir, "pids.current")); got != 37 {
+		t.Errorf("pids.current: got %d, want 37", got)
+	}
+	if got := cgroupCPUMax(dir, mount); got != 0.5 {
+		t.Errorf("cpu.max: got %g cores, want 0.5", got)
+	}
+	if got := cgroupCPUMax(mount, mount); got != 0 {
+		t.Errorf("root cpu.max: got %g cores, want 0 (max)", got)
+	}
+}
+
+func TestCgroupLimitsV1(t *testing.T) {
+	mounts := []cgroupMount{{root: "/", point: filepath.Join("testdata", "cgroup-v1", "memory"), fields: map[string]bool{"memory": true}}}
+	groups := parseProcCgroup(readTestdata(t, "proc-cgroup-v1.txt"))
+
+	dir, _, v2 := cgroupDir("memory", "memory.stat", groups, mounts)
+	if dir == "" || v2 {
+		t.Fatalf("got dir %q (v2 %v)", dir, v2)
+	}
+	stat := filepath.Join(dir, "memory.stat")
+	if got := readCgroupStat(stat, "hierarchical_memory_limit"); got != 512<<20 {
+		t.Errorf("hierarchical_memory_limit: got %d", got)
+	}
+	if got := readCgroupStat(stat, "missing"); got != 0 {
+		t.Errorf("missing key: got %d, want 0", got)
+	}
+	if got := readCgroupValue(filepath.Join(dir, "memory.limit_in_bytes")); got < cgroupNoLimit {
+		t.Errorf("unlimited v1 memory: got %d, want at least cgroupNoLimit", got)
+	}
+	if _, _, v2 := cgroupDir("cpu", "cpu.max", groups, mounts); v2 {
+		t.Error("cpu: no v2 mount, want no v2 dir")
+	}
+}
+
+func TestCgroupBreakdown(t *testing.T) {
+	got := cgroupBreakdown([]procInfo{
+		{Name: "systemd", CPU: 1, Memory: 10, Cgroup: "/init.scope"},
+		{Name: "nginx", CPU: 2, Memory: 100, Cgroup: "/system.slice/nginx.service"},
+		{Name: "nginx", CPU: 3, Memory: 100, Cgroup: "/system.slice/nginx.service"},
+		{Name: "bash", CPU: 1, Memory: 50},
+	})
+	want := []cgroupUsage{
+		{Group: "/system.slice/nginx.service", Procs: 2, CPU: 5, Memory: 200},
+		{Group: "/init.scope", Procs: 1, CPU: 1, Memory: 10},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
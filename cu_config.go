@@ -0,0 +1,128 @@
+// #############################################
+// CrunchyUtils - Config
+//
+// This file contains:
+// - Config file location & loading
+// - Default settings for all configurable tools
+//
+// The config is a plain JSON file. If it does not
+// exist yet, the defaults are written so the user
+// has something to edit.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cuConfig is the root of the config file
+type cuConfig struct {
+	Alerts   alertConfig    `json:"alerts"`
+	Pomodoro pomodoroConfig `json:"pomodoro"`
+	Alarms   alarmConfig    `json:"alarms"`
+	Display  displayConfig  `json:"display"`
+	Notify   notifyConfig   `json:"notify"`
+	Power    powerConfig    `json:"power"`
+}
+
+// config holds the active settings, defaults until loadConfig runs
+var config = defaultConfig()
+
+// defaultConfig returns the built-in settings
+func defaultConfig() cuConfig {
+	return cuConfig{
+		Alerts:   defaultAlertConfig(),
+		Pomodoro: defaultPomodoroConfig(),
+		Alarms:   defaultAlarmConfig(),
+		Display:  defaultDisplayConfig(),
+		Notify:   defaultNotifyConfig(),
+		Power:    defaultPowerConfig(),
+	}
+}
+
+// configDir returns the directory for config and data files.
+// Falls back to the current directory if no user config dir exists.
+func configDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(dir, "crunchyutils")
+}
+
+// configPath returns the config file location (-config overrides it)
+func configPath() string {
+	if *Flagconfig != "" {
+		return *Flagconfig
+	}
+	return filepath.Join(configDir(), "config.json")
+}
+
+// dataPath returns the path of a data file next to the config
+func dataPath(name string) string {
+	return filepath.Join(filepath.Dir(configPath()), name)
+}
+
+// loadConfig reads the config file into config. A missing file is
+// created with the default settings if create is set, headless
+// subcommands leave the disk alone.
+func loadConfig(create bool) error {
+	path := configPath()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if !create {
+			return nil
+		}
+		return saveConfig()
+	}
+	if err != nil {
+		return err
+	}
+	restrictConfigMode(path)
+
+	// Start from the defaults so missing keys keep sane values
+	cfg := defaultConfig()
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("config %s: %v", path, err)
+	}
+	config = cfg
+	return nil
+}
+
+// saveConfig writes the active config to disk
+func saveConfig() error {
+	path := configPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, configMode); err != nil {
+		return err
+	}
+	restrictConfigMode(path) // WriteFile keeps the mode of an existing file
+	return nil
+}
+
+// configMode keeps the config private, it holds notifier
+// passwords and tokens
+const configMode = 0o600
+
+// restrictConfigMode takes group and other access away from
+// config files written by older versions (0644)
+func restrictConfigMode(path string) {
+	if fi, err := os.Stat(path); err == nil && fi.Mode().Perm()&0o077 != 0 {
+		os.Chmod(path, configMode)
+	}
+}
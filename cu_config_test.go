@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestConfigFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no Unix permissions")
+	}
+	path := filepath.Join(t.TempDir(), "config.json")
+	saved, savedPath := config, *Flagconfig
+	defer func() { config, *Flagconfig = saved, savedPath }()
+	*Flagconfig = path
+
+	// An older version left it readable for everyone
+	if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(true); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0o600 {
+		t.Errorf("after load: got mode %o, want 600", fi.Mode().Perm())
+	}
+
+	os.Chmod(path, 0o644)
+	if err := saveConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0o600 {
+		t.Errorf("after save: got mode %o, want 600", fi.Mode().Perm())
+	}
+}
+
+func TestLoadConfigMergesTools(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	saved, savedPath := config, *Flagconfig
+	defer func() { config, *Flagconfig = saved, savedPath }()
+	*Flagconfig = path
+
+	data := `{"notify": {"tools": {"alarm": {"mute": true}, "backup": {"melody": "success"}}}}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(false); err != nil {
+		t.Fatal(err)
+	}
+	want := toolNotifyConfig{Melody: "alarm", Urgency: urgencyCritical, Mute: true}
+	if got := config.Notify.Tools["alarm"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("alarm: got %+v, want %+v", got, want)
+	}
+	if got := config.Notify.Tools["backup"]; got.Melody != "success" {
+		t.Errorf("backup: got %+v", got)
+	}
+	if got := config.Notify.Tools["timer"]; got.Melody != "chime" {
+		t.Errorf("timer: got %+v, want the default", got)
+	}
+	if got := defaultNotifyConfig().Tools["alarm"]; got.Mute {
+		t.Error("defaults were changed by loading")
+	}
+}
+
+func TestLoadConfigHeadless(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	saved, savedPath := config, *Flagconfig
+	defer func() { config, *Flagconfig = saved, savedPath }()
+	*Flagconfig = path
+
+	if err := loadConfig(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("headless load wrote %s", path)
+	}
+	if err := loadConfig(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("interactive load: %v", err)
+	}
+}
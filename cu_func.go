@@ -5,7 +5,7 @@
 // - UI helper functions (printing, prompts, spinners)
 // - Command execution helpers
 // - System information utilities (CPU, RAM, Disk, Uptime)
-// - Notification & terminal helpers
+// - Terminal helpers
 //
 // All functions here are platform-aware
 // and designed to be reused across the app.
@@ -19,19 +19,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/gen2brain/beeep"
+	"github.com/eiannone/keyboard"
 	"github.com/shirou/gopsutil/v3/cpu" // System infos
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/host"
 	"github.com/shirou/gopsutil/v3/mem"
+	psnet "github.com/shirou/gopsutil/v3/net"
 	"github.com/shirou/gopsutil/v3/process"
 )
 
@@ -49,19 +53,31 @@ func printSuccess(msg string) {
 	fmt.Printf("\n%s[INFO] %s%s\n", GREEN, RC, msg)
 }
 
+// eprintError is printError on stderr, headless subcommands
+// keep stdout for their output (status --json)
+func eprintError(msg string) {
+	fmt.Fprintf(os.Stderr, "%s[ERROR] %s%s\n", RED, RC, msg)
+}
+
 func line() {
-	if getcols > COLS+6 {
-		fmt.Printf("%s#%s-~ ~  ~%s\n", YELLOW, strings.Repeat("═", COLS-2), RC)
-	} else {
-		fmt.Printf("%s#%s#%s\n", YELLOW, strings.Repeat("═", COLS-2), RC)
-	}
+	fline(os.Stdout)
+}
+
+// fline writes the separator line to w (e.g. a screen)
+func fline(w io.Writer) {
+	cols, _ := termSize()
+	fmt.Fprintln(w, sepLine(YELLOW, cols-1))
 }
+
 func cmdline() {
-	if getcols > COLS+6 {
-		fmt.Printf("%s#%s-~ ~  ~%s\n", RED, strings.Repeat("═", COLS-2), RC)
-	} else {
-		fmt.Printf("%s#%s#%s\n", RED, strings.Repeat("═", COLS-2), RC)
-	}
+	cols, _ := termSize()
+	fmt.Println(sepLine(RED, cols-1))
+}
+
+// sepLine returns a separator like #════# that is width characters wide
+func sepLine(color string, width int) string {
+	width = max(width, 2)
+	return fmt.Sprintf("%s#%s#%s", color, strings.Repeat("═", width-2), RC)
 }
 
 // Pause waits for enter
@@ -86,9 +102,49 @@ func yesNo(question string) bool {
 }
 
 func printCommandTitle(name string) {
+	fprintCommandTitle(os.Stdout, name)
+}
+
+// fprintCommandTitle writes a centered title to w
+func fprintCommandTitle(w io.Writer, name string) {
+	cols, _ := termSize()
 	title := fmt.Sprintf("#   %s   #", name)
-	padding := strings.Repeat(" ", (COLS-len(title))/2)
-	fmt.Printf("%s%s%s%s\n", CYAN, padding, title, RC)
+	padding := strings.Repeat(" ", max(0, (cols-len(title))/2))
+	fmt.Fprintf(w, "%s%s%s%s\n", CYAN, padding, title, RC)
+}
+
+// isStopKey reports whether a raw key press should leave a running tool
+// (Enter, Esc, Ctrl+C or Q)
+func isStopKey(ev keyboard.KeyEvent) bool {
+	switch ev.Key {
+	case keyboard.KeyEnter, keyboard.KeyEsc, keyboard.KeyCtrlC:
+		return true
+	}
+	return ev.Rune == 'q' || ev.Rune == 'Q'
+}
+
+// waitMenuKey draws a menu and waits for a key press.
+// The menu is drawn again whenever the terminal is resized
+// or a background timer changes.
+func waitMenuKey(draw func()) (rune, keyboard.Key, error) {
+	draw()
+
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer keyboard.Close()
+
+	for {
+		select {
+		case ev := <-keys:
+			return ev.Rune, ev.Key, ev.Err
+		case <-termResized:
+			draw()
+		case <-timersChanged:
+			draw() // timer strip changed
+		}
+	}
 }
 
 // asyncSpinner displays a spinning "loading" animation in the terminal.
@@ -146,20 +202,6 @@ func runCommand(cmd []string) (string, error) {
 	return out, nil
 }
 
-func notifyAlarm() {
-	// system notification
-	beeep.Alert(
-		"CrunchyUtils",
-		"Alert!",
-		"",
-	)
-	beeep.Beep(600, 150)
-	time.Sleep(100 * time.Millisecond)
-	beeep.Beep(800, 200)
-	time.Sleep(100 * time.Millisecond)
-	beeep.Beep(1000, 300)
-}
-
 func clearScreen() {
 	var cmd *exec.Cmd
 	if goos == "windows" {
@@ -184,51 +226,139 @@ func formatTime(seconds int) string {
 	return fmt.Sprintf("%02d:%02d:%02d", h, m, s) // Format as HH:MM:SS with leading zeros
 }
 
-// parseTimeInput converts a time string in the format "HH:MM:SS" into total seconds.
-// Returns an error if the format is invalid or the total time is non-positive.
-func parseTimeInput(input string) (int, error) {
-	// Split input string by colon
-	parts := strings.Split(input, ":")
-	if len(parts) != 3 {
-		return 0, fmt.Errorf("invalid time format") // Must be HH:MM:SS
+// maxTimerDuration is the longest time any timer accepts
+const maxTimerDuration = 366 * 24 * time.Hour
+
+// parseTimeInput interprets a timer input relative to now and returns
+// the duration in whole seconds and the wall-clock target. Accepted:
+//
+//	1h30m, 90s, 5m      Go durations
+//	01:30:00, 25:00     HH:MM:SS and MM:SS
+//	5                   bare minutes
+//	at 23:00            next time the clock shows 23:00
+//	tomorrow 07:30      07:30 on the next day ("tomorrow at" works too)
+//
+// Zero, negative and overlong times (see maxTimerDuration) are rejected.
+func parseTimeInput(input string, now time.Time) (int, time.Time, error) {
+	in := strings.ToLower(strings.Join(strings.Fields(input), " "))
+	if in == "" {
+		return 0, time.Time{}, errors.New("no time entered")
 	}
 
-	// Parse hours
-	h, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return 0, err
+	// Absolute clock times
+	var days int
+	clock := false
+	switch {
+	case strings.HasPrefix(in, "tomorrow "):
+		in, days, clock = strings.TrimPrefix(strings.TrimPrefix(in, "tomorrow "), "at "), 1, true
+	case strings.HasPrefix(in, "at "):
+		in, clock = strings.TrimPrefix(in, "at "), true
+	}
+	if clock {
+		target, err := parseClock(in, now, days)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		secs, _, err := checkTimerDuration(target.Sub(now), now)
+		return secs, target, err
+	}
+
+	// Bare minutes
+	if n, err := strconv.ParseInt(in, 10, 64); err == nil {
+		if n > int64(maxTimerDuration/time.Minute) {
+			return 0, time.Time{}, fmt.Errorf("%q is too long", input)
+		}
+		return checkTimerDuration(time.Duration(n)*time.Minute, now)
+	}
+
+	// HH:MM:SS or MM:SS, only the first field may exceed 59
+	if strings.Contains(in, ":") {
+		parts := strings.Split(in, ":")
+		if len(parts) > 3 {
+			return 0, time.Time{}, fmt.Errorf("invalid time %q, use HH:MM:SS or MM:SS", input)
+		}
+		var total int64
+		for i, p := range parts {
+			n, err := strconv.ParseInt(p, 10, 64)
+			if err != nil || n < 0 {
+				return 0, time.Time{}, fmt.Errorf("invalid time %q, use HH:MM:SS or MM:SS", input)
+			}
+			if i > 0 && n > 59 {
+				return 0, time.Time{}, fmt.Errorf("invalid time %q, minutes and seconds go up to 59", input)
+			}
+			if n > int64(maxTimerDuration/time.Second) {
+				return 0, time.Time{}, fmt.Errorf("%q is too long", input)
+			}
+			total = total*60 + n
+		}
+		if total > int64(maxTimerDuration/time.Second) {
+			return 0, time.Time{}, fmt.Errorf("%q is too long", input)
+		}
+		return checkTimerDuration(time.Duration(total)*time.Second, now)
 	}
 
-	// Parse minutes
-	m, err := strconv.Atoi(parts[1])
+	// Go durations, ParseDuration rejects overflow itself
+	d, err := time.ParseDuration(in)
 	if err != nil {
-		return 0, err
+		return 0, time.Time{}, fmt.Errorf("invalid time %q, try 5m, 1h30m, 25:00 or at 17:45", input)
 	}
+	return checkTimerDuration(d, now)
+}
 
-	// Parse seconds
-	s, err := strconv.Atoi(parts[2])
+// parseClock returns the next time the clock shows HH:MM[:SS] after now,
+// days later if days > 0
+func parseClock(in string, now time.Time, days int) (time.Time, error) {
+	var at time.Time
+	var err error
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if at, err = time.ParseInLocation(layout, in, now.Location()); err == nil {
+			break
+		}
+	}
 	if err != nil {
-		return 0, err
+		return time.Time{}, fmt.Errorf("invalid clock time %q, use HH:MM", in)
 	}
 
-	// Calculate total seconds
-	total := h*3600 + m*60 + s
+	target := time.Date(now.Year(), now.Month(), now.Day()+days, at.Hour(), at.Minute(), at.Second(), 0, now.Location())
+	if days == 0 && !target.After(now) {
+		target = target.AddDate(0, 0, 1) // already passed today
+	}
+	return target, nil
+}
 
-	// Ensure total time is positive
-	if total <= 0 {
-		return 0, fmt.Errorf("time must be positive")
+// checkTimerDuration validates d and rounds it up to whole seconds
+func checkTimerDuration(d time.Duration, now time.Time) (int, time.Time, error) {
+	if d <= 0 {
+		return 0, time.Time{}, errors.New("time must be positive")
+	}
+	if d > maxTimerDuration {
+		return 0, time.Time{}, fmt.Errorf("time must not be longer than %d days", maxTimerDuration/(24*time.Hour))
 	}
+	secs := int((d + time.Second - 1) / time.Second)
+	return secs, now.Add(time.Duration(secs) * time.Second), nil
+}
 
-	return total, nil
+// describeTarget echoes a parsed timer input, e.g. "00:25:00, ends at 14:32:10"
+func describeTarget(secs int, target, now time.Time) string {
+	when := target.Format("15:04:05")
+	if y, m, d := target.Date(); y != now.Year() || m != now.Month() || d != now.Day() {
+		when = target.Format("Mon 02 Jan 15:04:05")
+	}
+	return fmt.Sprintf("%s, ends at %s", formatTime(secs), when)
 }
 
 func getCPUUsagePercent() string {
+	return fmt.Sprintf("%.0f%%", getCPUPercent())
+}
+
+// getCPUPercent returns the total CPU usage measured over one second
+func getCPUPercent() float64 {
 	// CPU percent 1000ms, Non-blocking Context
 	percentages, err := cpu.Percent(1000*time.Millisecond, false)
 	if err != nil || len(percentages) == 0 {
-		return "0%"
+		return 0
 	}
-	return fmt.Sprintf("%.0f%%", percentages[0])
+	return percentages[0]
 }
 
 // GetCPUCores returns the number of logical CPU cores
@@ -289,24 +419,36 @@ func getCurrentPartitionUsedBytes() (uint64, error) {
 	return usage.Used, nil
 }
 
-// getTopCPUProcesses returns the names of the top 5 CPU-consuming processes
-// The goal is to show "useful" user processes, not system noise
-func getTopCPUProcesses() []string {
+// procInfo is one running process as seen by the monitor
+type procInfo struct {
+	PID    int32   `json:"pid"`
+	Name   string  `json:"name"`
+	CPU    float64 `json:"cpu_percent"`
+	Memory uint64  `json:"memory_bytes"`
+	Cgroup string  `json:"cgroup,omitempty"`
+}
 
-	// Fetch all running processes via gopsutil
-	procs, err := process.Processes()
+// getProcesses returns all "useful" user processes sorted by CPU usage.
+// The goal is to show user processes, not system noise
+func getProcesses() ([]procInfo, error) {
+	all, err := getAllProcesses()
 	if err != nil {
-		// Fallback if process listing fails
-		return []string{"ERROR", "ERROR", "ERROR", "ERROR", "ERROR"}
+		return nil, err
 	}
+	return userProcesses(all), nil
+}
+
+// getAllProcesses returns every readable process sorted by CPU usage,
+// system ones included
+func getAllProcesses() ([]procInfo, error) {
 
-	// Small helper struct to bind process name with its CPU usage
-	type procCPU struct {
-		name string
-		cpu  float64
+	// Fetch all running processes via gopsutil
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
 	}
 
-	var list []procCPU
+	var list []procInfo
 
 	for _, p := range procs {
 
@@ -319,13 +461,33 @@ func getTopCPUProcesses() []string {
 		// CPUPercent returns CPU usage since last call
 		// Can be noisy or zero for sleeping processes
 		cpu, err := p.CPUPercent()
-		if err != nil || cpu <= 0 {
+		if err != nil {
 			continue
 		}
 
-		// Filter out known system / background processes
-		// Keeps the list readable and user-focused
-		lower := strings.ToLower(name)
+		// Resident memory, zero if we are not allowed to read it
+		var rss uint64
+		if mi, err := p.MemoryInfo(); err == nil {
+			rss = mi.RSS
+		}
+
+		list = append(list, procInfo{PID: p.Pid, Name: name, CPU: cpu, Memory: rss, Cgroup: procCgroupPath(p.Pid)})
+	}
+
+	// Sort processes by CPU usage descending
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CPU > list[j].CPU
+	})
+
+	return list, nil
+}
+
+// userProcesses filters out known system / background processes.
+// Keeps the list readable and user-focused
+func userProcesses(all []procInfo) []procInfo {
+	var list []procInfo
+	for _, p := range all {
+		lower := strings.ToLower(p.Name)
 		if strings.HasPrefix(lower, "system") ||
 			strings.HasPrefix(lower, "svchost") ||
 			strings.HasPrefix(lower, "init") ||
@@ -335,28 +497,52 @@ func getTopCPUProcesses() []string {
 			strings.HasPrefix(lower, "cu_main") {
 			continue
 		}
+		list = append(list, p)
+	}
+	return list
+}
 
-		// Trim long process names to avoid UI overflow
-		if len(name) > 18 {
-			name = name[:15] + "..."
-		}
+// getTopCPUProcesses returns the names of the top 5 CPU-consuming processes
+func getTopCPUProcesses() []string {
+	procs, err := getProcesses()
+	if err != nil {
+		// Fallback if process listing fails
+		return []string{"ERROR", "ERROR", "ERROR", "ERROR", "ERROR"}
+	}
 
-		list = append(list, procCPU{name, cpu})
+	var top []string
+	for _, p := range topProcesses(procs, 5) {
+		top = append(top, p.Name)
 	}
 
-	// Sort processes by CPU usage descending
-	sort.Slice(list, func(i, j int) bool {
-		return list[i].cpu > list[j].cpu
-	})
+	// Ensure fixed-length output for UI alignment
+	for len(top) < 5 {
+		top = append(top, "...")
+	}
 
-	var top []string
+	return top
+}
 
-	for _, p := range list {
+// topProcesses picks the n busiest processes with distinct names
+// from a list already sorted by CPU usage. Long names are shortened.
+func topProcesses(procs []procInfo, n int) []procInfo {
+	var top []procInfo
+
+	for _, p := range procs {
+		// Idle processes are not interesting
+		if p.CPU <= 0 || len(top) == n {
+			break
+		}
+
+		// Trim long process names to avoid UI overflow
+		if len(p.Name) > 18 {
+			p.Name = p.Name[:15] + "..."
+		}
 
 		// Prevent duplicate process names in the output
 		dup := false
 		for _, t := range top {
-			if t == p.name {
+			if t.Name == p.Name {
 				dup = true
 				break
 			}
@@ -365,17 +551,7 @@ func getTopCPUProcesses() []string {
 			continue
 		}
 
-		top = append(top, p.name)
-
-		// Stop once we have 5 entries
-		if len(top) == 5 {
-			break
-		}
-	}
-
-	// Ensure fixed-length output for UI alignment
-	for len(top) < 5 {
-		top = append(top, "...")
+		top = append(top, p)
 	}
 
 	return top
@@ -410,3 +586,223 @@ func getUptime() string {
 
 	return fmt.Sprintf("%dH:%02dM", h, m)
 }
+
+// diskInfo is the usage of one mounted partition
+type diskInfo struct {
+	Mount       string  `json:"mount"`
+	Total       uint64  `json:"total_bytes"`
+	Used        uint64  `json:"used_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// getDisks returns usage for every physical partition
+func getDisks() []diskInfo {
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return nil
+	}
+
+	var disks []diskInfo
+	for _, p := range partitions {
+		usage, err := disk.Usage(p.Mountpoint)
+		if err != nil || usage.Total == 0 {
+			continue
+		}
+		disks = append(disks, diskInfo{
+			Mount:       p.Mountpoint,
+			Total:       usage.Total,
+			Used:        usage.Used,
+			UsedPercent: usage.UsedPercent,
+		})
+	}
+	return disks
+}
+
+// netInfo holds the byte counters of one network interface
+type netInfo struct {
+	Name      string `json:"name"`
+	BytesSent uint64 `json:"bytes_sent"`
+	BytesRecv uint64 `json:"bytes_recv"`
+}
+
+// getNetCounters returns the counters of all interfaces except loopback
+func getNetCounters() []netInfo {
+	counters, err := psnet.IOCounters(true)
+	if err != nil {
+		return nil
+	}
+
+	var list []netInfo
+	for _, c := range counters {
+		lower := strings.ToLower(c.Name)
+		if lower == "lo" || strings.HasPrefix(lower, "loopback") {
+			continue
+		}
+		list = append(list, netInfo{Name: c.Name, BytesSent: c.BytesSent, BytesRecv: c.BytesRecv})
+	}
+	return list
+}
+
+// formatBytes formats a byte count like "1.5 GB"
+func formatBytes(b float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", b, units[i])
+}
+
+// sysSnapshot is one round of numeric system data.
+// The monitor renders it and the alert rules check it.
+type sysSnapshot struct {
+	Time         time.Time  `json:"time"`
+	CPUPercent   float64    `json:"cpu_percent"`
+	CPUCores     int        `json:"cpu_cores"`
+	Processes    []procInfo `json:"processes"`
+	RAMTotal     uint64     `json:"ram_total_bytes"`
+	RAMUsed      uint64     `json:"ram_used_bytes"`
+	RAMAvailable uint64     `json:"ram_available_bytes"`
+	Disks        []diskInfo `json:"disks"`
+	Net          []netInfo  `json:"net"`
+	NetRecvRate  float64    `json:"net_recv_bytes_per_sec"` // only set by the live collectors
+	NetSentRate  float64    `json:"net_sent_bytes_per_sec"`
+	Uptime       uint64     `json:"uptime_seconds"`
+
+	// Limits of our own cgroup (container, systemd slice)
+	Cgroup           cgroupInfo    `json:"cgroup"`
+	CgroupCPUPercent float64       `json:"cgroup_cpu_percent"`     // of the CPU limit
+	CgroupUsage      []cgroupUsage `json:"cgroup_usage,omitempty"` // all processes, system ones too
+}
+
+// MemoryLimited reports whether a cgroup limit is below the host RAM
+func (s sysSnapshot) MemoryLimited() bool {
+	return s.Cgroup.MemoryLimit > 0 && (s.RAMTotal == 0 || s.Cgroup.MemoryLimit < s.RAMTotal)
+}
+
+// CPULimited reports whether a cgroup CPU quota is below the host cores
+func (s sysSnapshot) CPULimited() bool {
+	return s.Cgroup.CPULimit > 0 && (s.CPUCores == 0 || s.Cgroup.CPULimit < float64(s.CPUCores))
+}
+
+// EffectiveRAM returns total, used and available RAM,
+// taken from the cgroup if that limit is lower than the host's
+func (s sysSnapshot) EffectiveRAM() (uint64, uint64, uint64) {
+	if !s.MemoryLimited() {
+		return s.RAMTotal, s.RAMUsed, s.RAMAvailable
+	}
+	total, used := s.Cgroup.MemoryLimit, min(s.Cgroup.MemoryUsed, s.Cgroup.MemoryLimit)
+	// The host can run out before our limit does
+	return total, used, min(total-used, s.RAMAvailable)
+}
+
+// EffectiveCPUPercent returns the CPU usage relative to what we may use
+func (s sysSnapshot) EffectiveCPUPercent() float64 {
+	if s.CPULimited() {
+		return s.CgroupCPUPercent
+	}
+	return s.CPUPercent
+}
+
+// RAMPercent returns the used RAM in percent of the effective limit
+func (s sysSnapshot) RAMPercent() float64 {
+	total, used, _ := s.EffectiveRAM()
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100
+}
+
+// cgroupCPUPercent returns the CPU usage between two cgroup readings
+// in percent of the CPU limit (or of all cores without limit)
+func cgroupCPUPercent(cur, prev cgroupInfo, seconds float64, cores int) float64 {
+	limit := cur.CPULimit
+	if limit <= 0 {
+		limit = float64(cores)
+	}
+	if seconds <= 0 || limit <= 0 || cur.CPUUsage < prev.CPUUsage || prev.CPUUsage == 0 {
+		return 0
+	}
+	used := float64(cur.CPUUsage-prev.CPUUsage) / 1e6 // usec -> seconds
+	return min(used/seconds/limit*100, 100)
+}
+
+// NetRates returns download and upload in bytes/s since prev.
+// Returns zero if prev is empty or from the future.
+func (s sysSnapshot) NetRates(prev sysSnapshot) (float64, float64) {
+	dt := s.Time.Sub(prev.Time).Seconds()
+	if prev.Time.IsZero() || dt <= 0 {
+		return 0, 0
+	}
+
+	var recv, sent float64
+	for _, cur := range s.Net {
+		for _, old := range prev.Net {
+			// Counters can reset (interface restart), skip those
+			if old.Name != cur.Name || cur.BytesRecv < old.BytesRecv || cur.BytesSent < old.BytesSent {
+				continue
+			}
+			recv += float64(cur.BytesRecv - old.BytesRecv)
+			sent += float64(cur.BytesSent - old.BytesSent)
+		}
+	}
+	return recv / dt, sent / dt
+}
+
+// takeSnapshot collects CPU, processes, RAM, disks, network and cgroup limits.
+// Blocks for about one second because of the CPU measurement.
+func takeSnapshot() sysSnapshot {
+	snap := sysSnapshot{Time: time.Now()}
+
+	// The cgroup CPU time is read around the CPU measurement
+	before, start := readCgroup(), time.Now()
+	snap.CPUPercent = getCPUPercent()
+	if cores, err := cpu.Counts(true); err == nil {
+		snap.CPUCores = cores
+	}
+	snap.Cgroup = readCgroup()
+	snap.CgroupCPUPercent = cgroupCPUPercent(snap.Cgroup, before, time.Since(start).Seconds(), snap.CPUCores)
+	if all, err := getAllProcesses(); err == nil {
+		snap.Processes = userProcesses(all)
+		snap.CgroupUsage = cgroupBreakdown(all)
+	}
+
+	if vm, err := mem.VirtualMemory(); err == nil {
+		snap.RAMTotal = vm.Total
+		snap.RAMUsed = vm.Used
+		snap.RAMAvailable = vm.Available
+	}
+
+	snap.Disks = getDisks()
+	snap.Net = getNetCounters()
+	snap.Uptime, _ = host.Uptime()
+	return snap
+}
+
+// socketProto names a socket type/family pair like "tcp6"
+func socketProto(typ, family uint32) string {
+	proto := "?"
+	switch typ {
+	case syscall.SOCK_STREAM:
+		proto = "tcp"
+	case syscall.SOCK_DGRAM:
+		proto = "udp"
+	}
+	if family == syscall.AF_INET6 {
+		proto += "6"
+	}
+	return proto
+}
+
+// formatSockAddr joins IP and port, "*" for unset parts
+func formatSockAddr(ip string, port uint32) string {
+	if ip == "" {
+		ip = "*"
+	}
+	p := "*"
+	if port != 0 {
+		p = strconv.Itoa(int(port))
+	}
+	return net.JoinHostPort(ip, p)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeInput(t *testing.T) {
+	// Monday 14:00:00
+	now := time.Date(2025, 3, 10, 14, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		input   string
+		secs    int
+		target  time.Time
+		wantErr bool
+	}{
+		// Go durations
+		{input: "5m", secs: 300},
+		{input: "90s", secs: 90},
+		{input: "1h30m", secs: 5400},
+		{input: "1.5h", secs: 5400},
+		{input: "1500ms", secs: 2}, // rounded up
+		{input: " 2H ", secs: 7200},
+
+		// Colon formats
+		{input: "01:30:00", secs: 5400},
+		{input: "25:00", secs: 1500},
+		{input: "0:05", secs: 5},
+		{input: "90:00", secs: 5400}, // the first field may exceed 59
+		{input: "00:60:00", wantErr: true},
+		{input: "10:75", wantErr: true},
+		{input: "1:2:3:4", wantErr: true},
+		{input: "aa:bb", wantErr: true},
+		{input: "00:00:00", wantErr: true},
+
+		// Bare minutes
+		{input: "5", secs: 300},
+		{input: "0", wantErr: true},
+		{input: "-5", wantErr: true},
+
+		// Clock times
+		{input: "at 17:45", secs: 3*3600 + 45*60, target: time.Date(2025, 3, 10, 17, 45, 0, 0, time.Local)},
+		{input: "AT 17:45:30", secs: 3*3600 + 45*60 + 30, target: time.Date(2025, 3, 10, 17, 45, 30, 0, time.Local)},
+		{input: "at 09:00", secs: 19 * 3600, target: time.Date(2025, 3, 11, 9, 0, 0, 0, time.Local)}, // passed, tomorrow
+		{input: "at 14:00", secs: 24 * 3600, target: time.Date(2025, 3, 11, 14, 0, 0, 0, time.Local)},
+		{input: "tomorrow 07:30", secs: 17*3600 + 30*60, target: time.Date(2025, 3, 11, 7, 30, 0, 0, time.Local)},
+		{input: "tomorrow at 23:00", secs: 33 * 3600, target: time.Date(2025, 3, 11, 23, 0, 0, 0, time.Local)},
+		{input: "at 25:00", wantErr: true},
+		{input: "at noon", wantErr: true},
+
+		// Overflow and garbage
+		{input: "9999999999999999999h", wantErr: true},
+		{input: "99999999999", wantErr: true},
+		{input: "9999:00:00", wantErr: true},
+		{input: "9999999999999999999:00", wantErr: true},
+		{input: "400d", wantErr: true},
+		{input: "-5m", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			secs, target, err := parseTimeInput(tt.input, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %d seconds, want an error", secs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if secs != tt.secs {
+				t.Errorf("got %d seconds, want %d", secs, tt.secs)
+			}
+			want := tt.target
+			if want.IsZero() {
+				want = now.Add(time.Duration(tt.secs) * time.Second)
+			}
+			if !target.Equal(want) {
+				t.Errorf("got target %v, want %v", target, want)
+			}
+		})
+	}
+}
+
+func TestDescribeTarget(t *testing.T) {
+	now := time.Date(2025, 3, 10, 14, 0, 0, 0, time.Local)
+
+	if got, want := describeTarget(1500, now.Add(25*time.Minute), now), "00:25:00, ends at 14:25:00"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := describeTarget(61200, now.Add(17*time.Hour), now), "17:00:00, ends at Tue 11 Mar 07:00:00"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+// #############################################
+// CrunchyUtils - Terminal Layout
+//
+// This file contains:
+// - Current terminal size (kept up to date on resize)
+// - Layout classes: compact, normal, wide
+// - Helpers for ANSI-aware text width
+//
+// The UI is designed for COLS x LINES but never forces
+// the window to that size. Smaller terminals get a
+// compact layout, bigger ones get more content.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/term"
+)
+
+var (
+	termCols, termLines atomic.Int32             // 0 = unknown
+	termResized         = make(chan struct{}, 1) // signalled on every size change
+)
+
+// updateTermSize reads the terminal size and reports whether it changed
+func updateTermSize() bool {
+	cols, lines, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || cols <= 0 || lines <= 0 {
+		return false
+	}
+
+	oldCols := termCols.Swap(int32(cols))
+	oldLines := termLines.Swap(int32(lines))
+	if int(oldCols) == cols && int(oldLines) == lines {
+		return false
+	}
+
+	// Non-blocking, one pending notification is enough
+	select {
+	case termResized <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// termSize returns the terminal size, COLS x LINES if unknown
+func termSize() (int, int) {
+	cols, lines := int(termCols.Load()), int(termLines.Load())
+	if cols <= 0 || lines <= 0 {
+		return COLS, LINES
+	}
+	return cols, lines
+}
+
+// compactLayout is true for terminals smaller than COLS x LINES
+func compactLayout() bool {
+	cols, lines := termSize()
+	return cols < COLS || lines < LINES
+}
+
+// wideLayout is true if there is room next to the normal layout
+func wideLayout() bool {
+	cols, _ := termSize()
+	return cols > COLS+6
+}
+
+// visibleLen returns the printed width of s, ANSI codes excluded
+func visibleLen(s string) int {
+	n := 0
+	inEsc := false
+	for _, r := range s {
+		switch {
+		case inEsc:
+			// CSI sequences end with a letter
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				inEsc = false
+			}
+		case r == '\033':
+			inEsc = true
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// truncateVisible cuts s to width printed characters, keeping ANSI codes
+func truncateVisible(s string, width int) string {
+	if visibleLen(s) <= width {
+		return s
+	}
+
+	var sb strings.Builder
+	n := 0
+	inEsc := false
+	for _, r := range s {
+		switch {
+		case inEsc:
+			sb.WriteRune(r)
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				inEsc = false
+			}
+		case r == '\033':
+			inEsc = true
+			sb.WriteRune(r)
+		case n < width:
+			sb.WriteRune(r)
+			n++
+		}
+		// Past width only escapes (like color resets) are kept
+	}
+	return sb.String()
+}
+
+// padVisible pads s with spaces to width printed characters
+func padVisible(s string, width int) string {
+	if n := visibleLen(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
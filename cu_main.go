@@ -28,18 +28,17 @@ import (
 	"os/exec" // Execute external commands
 	"os/user" // Current user info
 	"runtime" // OS detection
+	"strconv" // Number parsing
 	"strings" // String utilities
 	"time"    // Timing utilities
 
 	"github.com/eiannone/keyboard" // Raw keyboard input
-	"github.com/gen2brain/beeep"   // System beep / notifications
-	"golang.org/x/term"            // Terminal size detection
 )
 
 const (
 	CU_VERSION = "0.18"
 
-	// Target terminal size
+	// Reference layout size (smaller terminals get a compact layout)
 	COLS  = 70
 	LINES = 27
 
@@ -52,21 +51,23 @@ const (
 	GREEN  = "\033[32m"
 	BLUE   = "\033[34m"
 	CYAN   = "\033[36m"
+	GREY   = "\033[90m"
 	RC     = "\033[0m"
 )
 
 var (
-	getcols, getlines int                           // Detected terminal size
-	consoleRunning    = true                        // Main loop control
-	goos              = runtime.GOOS                // Cached OS string
-	reader            = bufio.NewReader(os.Stdin)   // Global input reader
-	SPINNERFRAMES     = []rune{'|', '/', '-', '\\'} // Spinner animation frames
-	CMDWAIT           = 1 * time.Second             // Artificial delay between commands
+	consoleRunning = true                        // Main loop control
+	goos           = runtime.GOOS                // Cached OS string
+	reader         = bufio.NewReader(os.Stdin)   // Global input reader
+	SPINNERFRAMES  = []rune{'|', '/', '-', '\\'} // Spinner animation frames
+	CMDWAIT        = 1 * time.Second             // Artificial delay between commands
 	// CLI flags
 	Flagversion = flag.Bool("version", false, "Show version")
-	Flagnoinit  = flag.Bool("no-init", false, "Dont resize window, etc.")
+	Flagnoinit  = flag.Bool("no-init", false, "Dont set window title, etc.")
 	Flagskip    = flag.Bool("skip", false, "Skip all delays")
 	Flagnoadmin = flag.Bool("no-admin", false, "Skip admin/root request")
+	Flagconfig  = flag.String("config", "", "Path to config file")
+	Flagreplay  = flag.String("replay", "", "Replay a system monitor recording (.jsonl.gz)")
 )
 
 //
@@ -179,11 +180,7 @@ func startup() {
 	}
 	fmt.Printf("\n%s**************************%s", GREEN, RC)
 	printSuccess("LOADING SUCCESSFUL!")
-	beeep.Beep(600, 150)
-	time.Sleep(100 * time.Millisecond)
-	beeep.Beep(800, 200)
-	time.Sleep(100 * time.Millisecond)
-	beeep.Beep(1000, 300)
+	playMelody("default")
 }
 
 //
@@ -203,55 +200,36 @@ func initApp() {
 	// Set terminal title
 	fmt.Printf("\033]0;CrunchyUtils\007")
 
-	// Try to resize terminal
-	switch goos {
-	case "windows":
-		// CMD title and size
-		runCommand([]string{"cmd", "/C", "title CrunchyUtils"})
-		// PowerShell resize
-		psCmd := fmt.Sprintf(
-			`$Host.UI.RawUI.WindowSize = New-Object System.Management.Automation.Host.Size(%d,%d)`,
-			COLS, LINES,
-		)
-		runCommand([]string{"powershell", "-Command", psCmd})
-	}
-	// ANSI resize (best effort, also fallback but this works 90% of the time)
-	fmt.Printf("\033[8;%d;%dt", LINES, COLS)
-
-	// ---- VERIFY SIZE ----
-	getcols, getlines = 0, 0
-	sizeErr := error(nil)
-
-	// NEW SIMPLE WAY: term.GetSize
-	cols, lines, err := term.GetSize(int(os.Stdout.Fd()))
-	if err != nil {
-		sizeErr = err
-	} else {
-		getcols = cols
-		getlines = lines
-	}
-
-	if sizeErr != nil || getcols == 0 || getlines == 0 {
-		printError("Could not detect terminal size")
+	// The layout follows the terminal, the window is never resized
+	if !updateTermSize() {
+		printError("Could not detect terminal size, assuming " + fmt.Sprintf("%dx%d", COLS, LINES))
 		time.Sleep(2 * time.Second)
+		return
 	}
 
-	if getcols != COLS || getlines != LINES {
-		printError(fmt.Sprintf("Terminal size mismatch got: %dx%d expected: %dx%d", getcols, getlines, COLS, LINES))
-		time.Sleep(2 * time.Second)
+	cols, lines := termSize()
+	if compactLayout() {
+		printInfo(fmt.Sprintf("Terminal size: %dx%d (compact layout, %dx%d recommended)", cols, lines, COLS, LINES))
 	} else {
-		printInfo(fmt.Sprintf("Terminal size OK: %dx%d", getcols, getlines))
+		printInfo(fmt.Sprintf("Terminal size: %dx%d", cols, lines))
 	}
 }
 
 func timerMenu() {
 	for {
 		printCommandTitle("Timer/Stopwatch")
+		printTimers()
+		line()
 		fmt.Printf(" [0] - %sReturn%s\n", RED, RC)
 		fmt.Printf(" [1] - %sTimer%s\n", YELLOW, RC)
 		fmt.Printf(" [2] - %sStopwatch%s\n", YELLOW, RC)
+		fmt.Printf(" [3] - %sBackground timer%s\n", YELLOW, RC)
+		fmt.Printf(" [4] - %sBackground stopwatch%s\n", YELLOW, RC)
+		fmt.Printf(" [5] - %sAlarm clock%s\n", YELLOW, RC)
+		fmt.Printf(" [6] - %sPomodoro%s\n", YELLOW, RC)
+		fmt.Printf(" [C] - %sCancel a background timer%s\n", YELLOW, RC)
 		line()
-		fmt.Printf("Press key (0-2)\n")
+		fmt.Printf("Press key (0-6, C)\n")
 
 		c, _, err := keyboard.GetSingleKey()
 		if err != nil {
@@ -262,18 +240,44 @@ func timerMenu() {
 		case '0', 27: // Return or ESC
 			return
 		case '1':
-			fmt.Printf("Enter time (HH:MM:SS)%s", PROMPT)
-			input, _ := reader.ReadString('\n')
-			input = strings.TrimSpace(input)
-			secs, err := parseTimeInput(input)
-			if err != nil {
-				fmt.Printf("\nInvalid time format\n")
-				pause()
+			secs, _, ok := askTimerTime("Enter time")
+			if !ok {
 				continue
 			}
 			countdownTimer(secs)
 		case '2':
 			stopwatch()
+		case '3':
+			name := askTimerName("Timer")
+			_, target, ok := askTimerTime("Enter time")
+			if !ok {
+				continue
+			}
+			t := addTimer(name, timerCountdown, target)
+			printSuccess(t.Name + " started")
+		case '4':
+			t := addTimer(askTimerName("Stopwatch"), timerStopwatch, time.Time{})
+			printSuccess(t.Name + " started")
+		case '5':
+			alarmMenu()
+			continue
+		case '6':
+			pomodoroMenu()
+			continue
+		case 'c', 'C':
+			fmt.Printf("Timer number to cancel%s", PROMPT)
+			input, _ := reader.ReadString('\n')
+			id, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(input), "#"))
+			var t *bgTimer
+			if err == nil {
+				t = cancelTimer(id)
+			}
+			if t == nil {
+				printError("No such timer")
+				pause()
+				continue
+			}
+			printInfo(fmt.Sprintf("Cancelled %s (%s)", t.Name, t.Status(time.Now())))
 		default:
 			fmt.Printf("\nInvalid Option\n")
 			time.Sleep(2 * time.Second)
@@ -283,14 +287,50 @@ func timerMenu() {
 	}
 }
 
+// askTimerTime prompts for a timer time (see parseTimeInput) and echoes
+// what it understood. ok is false after an invalid input.
+func askTimerTime(prompt string) (int, time.Time, bool) {
+	fmt.Printf("%s (e.g. 5m, 1h30m, 25:00, at 17:45)%s", prompt, PROMPT)
+	input, _ := reader.ReadString('\n')
+
+	now := time.Now()
+	secs, target, err := parseTimeInput(strings.TrimSpace(input), now)
+	if err != nil {
+		printError(err.Error())
+		pause()
+		return 0, time.Time{}, false
+	}
+	printInfo(describeTarget(secs, target, now))
+	return secs, target, true
+}
+
+// askTimerName prompts for a timer name, kind plus number if left empty
+func askTimerName(kind string) string {
+	fmt.Printf("Name (optional)%s", PROMPT)
+	name, _ := reader.ReadString('\n')
+	if name = strings.TrimSpace(name); name != "" {
+		return name
+	}
+	return fmt.Sprintf("%s %d", kind, len(listTimers())+1)
+}
+
 func powerMenu() {
 	for {
-		printCommandTitle("Shutdown/Reboot Timer")
+		pending := listScheduledPower()
+		supported := powerSupport()
+		printCommandTitle("Power")
+		printScheduledPower(pending)
+		line()
 		fmt.Printf(" [0] - %sReturn%s\n", RED, RC)
 		fmt.Printf(" [1] - %sShutdown Timer%s\n", YELLOW, RC)
 		fmt.Printf(" [2] - %sReboot Timer%s\n", YELLOW, RC)
+		fmt.Printf(" [3] - %sSchedule shutdown with the OS%s (survives exit)\n", YELLOW, RC)
+		fmt.Printf(" [4] - %sSchedule reboot with the OS%s (survives exit)\n", YELLOW, RC)
+		printPowerActions(supported)
+		fmt.Printf(" [T] - %sWhen a job finishes or the system is idle%s\n", YELLOW, RC)
+		fmt.Printf(" [C] - %sCancel a scheduled shutdown/reboot%s\n", YELLOW, RC)
 		line()
-		fmt.Printf("Press key (0-2)\n")
+		fmt.Printf("Press key (0-9, T, C)\n")
 
 		c, _, err := keyboard.GetSingleKey()
 		if err != nil {
@@ -315,6 +355,17 @@ func powerMenu() {
 				toption = "Lreboot"
 			}
 			powerTimer("shutdown/reboot", toption)
+		case '3':
+			schedulePowerPrompt("poweroff")
+		case '4':
+			schedulePowerPrompt("reboot")
+		case '5', '6', '7', '8', '9':
+			a, _ := findPowerAction(c)
+			powerActionMenu(a, supported[c])
+		case 't', 'T':
+			powerTriggerMenu()
+		case 'c', 'C':
+			cancelPowerPrompt(pending)
 		default:
 			fmt.Printf("\nInvalid Option\n")
 			time.Sleep(2 * time.Second)
@@ -325,7 +376,7 @@ func powerMenu() {
 }
 
 func crunchytext() {
-	if getcols > COLS+6 {
+	if wideLayout() {
 		fmt.Printf(`%s██      ███       ███  ████  ██   ███  ███      ███  ████  ██  ████  █▓▓▓▒▒▒
 ▓  ▓▓▓▓  ▓▓  ▓▓▓▓  ▓▓  ▓▓▓▓  ▓▓    ▓▓  ▓▓  ▓▓▓▓  ▓▓  ▓▓▓▓  ▓▓▓  ▓▓  ▓▓▓▓ ▓▓ ▒
 ▒  ▒▒▒▒▒▒▒▒       ▒▒▒  ▒▒▒▒  ▒▒  ▒  ▒  ▒▒  ▒▒▒▒▒▒▒▒        ▒▒▒▒    ▒▒▒▒ ▒▒ ▒
@@ -362,6 +413,37 @@ func showBanner() {
 
 	clearScreen()
 	line()
+
+	// Small terminals: plain header and a single column menu
+	if compactLayout() {
+		fmt.Printf("%sCrunchyUtils %s%s  By: Knuspii, (M)\n", YELLOW, CU_VERSION, RC)
+		fmt.Printf("Uptime: %s | Used-RAM: %s | Time: %s\n", uptime, usedRam, now.Format("15:04"))
+		if strip := timerStrip(); strip != "" {
+			fmt.Printf("Timer: %s\n", strip)
+		}
+		if power := powerStatusLine(); power != "" {
+			fmt.Printf("Power: %s\n", power)
+		}
+		line()
+		fmt.Printf("Tools:\n")
+		fmt.Printf(" [1] - %sSystem monitor%s\n", YELLOW, RC)
+		fmt.Printf(" [2] - %sSystem cleanup%s\n", YELLOW, RC)
+		fmt.Printf(" [3] - %sClipboard logger%s\n", YELLOW, RC)
+		fmt.Printf(" [4] - %sTimer and stopwatch%s\n", YELLOW, RC)
+		fmt.Printf(" [5] - %sShutdown timer%s\n", YELLOW, RC)
+		fmt.Printf(" [6] - %sWeather%s\n", YELLOW, RC)
+		fmt.Printf(" [7] - %sDomain infos%s\n", YELLOW, RC)
+		fmt.Printf(" [8] - %sRestart display-manager%s\n", YELLOW, RC)
+		fmt.Printf(" [9] - %sReboot to BIOS%s\n", YELLOW, RC)
+		fmt.Printf(" [P] - %sProcess tree%s\n", YELLOW, RC)
+		fmt.Printf(" [N] - %sNetwork sockets%s\n", YELLOW, RC)
+		fmt.Printf(" [S] - %sServices%s\n", YELLOW, RC)
+		fmt.Printf(" [U] - %sUpdate%s  [I] - %sInfos%s  [R] - %sRestart%s  [Q] - %sQuit%s\n", YELLOW, RC, YELLOW, RC, YELLOW, RC, RED, RC)
+		line()
+		fmt.Printf("Press key to launch a tool (1-9)\n")
+		return
+	}
+
 	crunchytext()
 	fmt.Printf(`%s
 █  ████  ██        ██        ██  █████████      ██ By: Knuspii, (M)
@@ -370,11 +452,17 @@ func showBanner() {
 ▓  ▓▓▓▓  ▓▓▓▓▓  ▓▓▓▓▓▓▓▓  ▓▓▓▓▓  ▓▓▓▓▓▓▓▓▓▓▓▓▓▓  ▓ Used-RAM : %s
 ██      ██████  █████        ██        ███      ██ Time     : %s
 `, YELLOW, CU_VERSION, uptime, usedRam, now.Format("15:04"))
+	if strip := timerStrip(); strip != "" {
+		fmt.Printf("%sTimer%s: %s\n", YELLOW, RC, strip)
+	}
+	if power := powerStatusLine(); power != "" {
+		fmt.Printf("%sPower%s: %s\n", YELLOW, RC, power)
+	}
 	line()
 	fmt.Printf("Tools:\n")
-	fmt.Printf("  [1]  - %sSystem monitor%s\n", YELLOW, RC)
-	fmt.Printf("  [2]  - %sDoes a system cleanup%s\n", YELLOW, RC)
-	fmt.Printf("  [3]  - %sClipboard logger%s\n", YELLOW, RC)
+	fmt.Printf("  [1]  - %sSystem monitor%s                            [P]  - %sProcess tree%s\n", YELLOW, RC, YELLOW, RC)
+	fmt.Printf("  [2]  - %sDoes a system cleanup%s                     [N]  - %sNetwork sockets%s\n", YELLOW, RC, YELLOW, RC)
+	fmt.Printf("  [3]  - %sClipboard logger%s                          [S]  - %sServices%s\n", YELLOW, RC, YELLOW, RC)
 	fmt.Printf("  [4]  - %sTimer and stopwatch%s\n", YELLOW, RC)
 	fmt.Printf("  [5]  - %sShutdown timer%s\n", YELLOW, RC)
 	fmt.Printf("  [6]  - %sShow weather infos%s                        [U]  - %sUpdate%s\n", YELLOW, RC, YELLOW, RC)
@@ -401,6 +489,42 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Subcommands run headless and exit
+	headless := flag.Arg(0) != ""
+	if err := loadConfig(!headless); err != nil {
+		msg := fmt.Sprintf("Config: %v (using defaults)", err)
+		if headless {
+			eprintError(msg)
+		} else {
+			printError(msg)
+		}
+	}
+
+	switch flag.Arg(0) {
+	case "":
+	case "serve-metrics":
+		os.Exit(serveMetricsCmd(flag.Args()[1:]))
+	case "status":
+		os.Exit(statusCmd(flag.Args()[1:]))
+	case "notify":
+		os.Exit(notifyCmd(flag.Args()[1:]))
+	default:
+		eprintError("Unknown command: " + flag.Arg(0))
+		os.Exit(2)
+	}
+
+	// Replay and the menus follow the real terminal size
+	watchTermSize()
+
+	// Replay runs standalone, no splash or admin needed
+	if *Flagreplay != "" {
+		replayMonitor(*Flagreplay)
+		return
+	}
+
+	if err := startAlarmClock(); err != nil {
+		printError(err.Error())
+	}
 	startup()
 
 	if err := keyboard.Open(); err != nil {
@@ -409,9 +533,8 @@ func main() {
 	defer keyboard.Close()
 
 	for consoleRunning {
-		showBanner()
-
-		char, _, err := keyboard.GetSingleKey()
+		// Banner is drawn again when the terminal is resized
+		char, _, err := waitMenuKey(showBanner)
 		if err != nil {
 			fmt.Println("Error reading key:", err)
 			pause()
@@ -450,6 +573,24 @@ func main() {
 			if yesNo("Sure you want to reboot to BIOS?") {
 				rebootBIOS()
 			}
+		case 'p', 'P':
+			processTree()
+		case 'n', 'N':
+			socketViewer()
+		case 's', 'S':
+			serviceManager()
+		case 'a', 'A':
+			if !ackAlarm() {
+				printInfo("No alarm is ringing")
+				time.Sleep(time.Second)
+			}
+		case 'z', 'Z':
+			if again, ok := snoozeAlarm(); ok {
+				printInfo("Snoozed until " + again.Format("15:04"))
+			} else {
+				printInfo("No alarm is ringing")
+			}
+			time.Sleep(time.Second)
 		case 'u', 'U':
 			printCommandTitle("Update CrunchyUtils")
 			printInfo("CURRENTLY UNAVAILABLE")
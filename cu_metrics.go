@@ -0,0 +1,177 @@
+// #############################################
+// CrunchyUtils - Prometheus Metrics Endpoint
+//
+// This file contains:
+// - The headless "serve-metrics" subcommand
+// - Prometheus text exposition of a sysSnapshot
+//
+// Usage:
+//   crunchyutils serve-metrics --listen :9850
+//
+// Data is collected in the background by the
+// monitor collectors, so a scrape never blocks on
+// the one second CPU measurement.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricsTopProcs is how many processes get exported per scrape
+const metricsTopProcs = 10
+
+// serveMetricsCmd runs the serve-metrics subcommand and returns the exit code
+func serveMetricsCmd(args []string) int {
+	fs := flag.NewFlagSet("serve-metrics", flag.ContinueOnError)
+	listen := fs.String("listen", ":9850", "Address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// Same background collectors as the monitor, the first
+	// round is awaited so the first scrape is not empty
+	live := startCollectors(context.Background())
+	time.Sleep(1500 * time.Millisecond)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		s, _ := live.get()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, s)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "CrunchyUtils %s - metrics at /metrics\n", CU_VERSION)
+	})
+
+	printInfo(fmt.Sprintf("Serving metrics on %s/metrics", *listen))
+	if err := http.ListenAndServe(*listen, mux); err != nil {
+		eprintError(err.Error())
+		return 1
+	}
+	return 0
+}
+
+// promWriter writes metric families in the Prometheus text format
+type promWriter struct {
+	w io.Writer
+}
+
+// family writes the HELP and TYPE header of a metric
+func (p promWriter) family(name, typ, help string) {
+	fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes one value, labels are given as key/value pairs
+func (p promWriter) sample(name string, value float64, labels ...string) {
+	var sb strings.Builder
+	sb.WriteString(name)
+	if len(labels) > 0 {
+		sb.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(&sb, "%s=\"%s\"", labels[i], promEscape(labels[i+1]))
+		}
+		sb.WriteByte('}')
+	}
+	fmt.Fprintf(p.w, "%s %s\n", sb.String(), strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// promEscape escapes a label value (backslash, quote, newline)
+func promEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// writeMetrics renders all values of snap as Prometheus metrics
+func writeMetrics(w io.Writer, snap sysSnapshot) {
+	p := promWriter{w}
+
+	p.family("crunchyutils_build_info", "gauge", "CrunchyUtils version, always 1.")
+	p.sample("crunchyutils_build_info", 1, "version", CU_VERSION)
+
+	p.family("crunchyutils_uptime_seconds", "gauge", "System uptime in seconds.")
+	p.sample("crunchyutils_uptime_seconds", float64(snap.Uptime))
+
+	// CPU
+	p.family("crunchyutils_cpu_cores", "gauge", "Number of logical CPU cores.")
+	p.sample("crunchyutils_cpu_cores", float64(snap.CPUCores))
+	p.family("crunchyutils_cpu_usage_percent", "gauge", "Total CPU usage in percent.")
+	p.sample("crunchyutils_cpu_usage_percent", snap.CPUPercent)
+
+	// RAM
+	p.family("crunchyutils_memory_total_bytes", "gauge", "Total RAM in bytes.")
+	p.sample("crunchyutils_memory_total_bytes", float64(snap.RAMTotal))
+	p.family("crunchyutils_memory_used_bytes", "gauge", "Used RAM in bytes.")
+	p.sample("crunchyutils_memory_used_bytes", float64(snap.RAMUsed))
+	p.family("crunchyutils_memory_available_bytes", "gauge", "Available RAM in bytes.")
+	p.sample("crunchyutils_memory_available_bytes", float64(snap.RAMAvailable))
+
+	// Cgroup limits, 0 means no limit
+	if cg := snap.Cgroup; cg.Version > 0 {
+		p.family("crunchyutils_cgroup_memory_limit_bytes", "gauge", "Memory limit of our cgroup in bytes, 0 if unlimited.")
+		p.sample("crunchyutils_cgroup_memory_limit_bytes", float64(cg.MemoryLimit))
+		p.family("crunchyutils_cgroup_memory_used_bytes", "gauge", "Memory used by our cgroup in bytes.")
+		p.sample("crunchyutils_cgroup_memory_used_bytes", float64(cg.MemoryUsed))
+		p.family("crunchyutils_cgroup_cpu_limit_cores", "gauge", "CPU quota of our cgroup in cores, 0 if unlimited.")
+		p.sample("crunchyutils_cgroup_cpu_limit_cores", cg.CPULimit)
+		p.family("crunchyutils_cgroup_cpu_usage_percent", "gauge", "CPU usage of our cgroup in percent of its limit.")
+		p.sample("crunchyutils_cgroup_cpu_usage_percent", snap.CgroupCPUPercent)
+		p.family("crunchyutils_cgroup_pids_limit", "gauge", "Process limit of our cgroup, 0 if unlimited.")
+		p.sample("crunchyutils_cgroup_pids_limit", float64(cg.PidsLimit))
+		p.family("crunchyutils_cgroup_pids_current", "gauge", "Processes in our cgroup.")
+		p.sample("crunchyutils_cgroup_pids_current", float64(cg.PidsCurrent))
+	}
+
+	// Disks
+	p.family("crunchyutils_disk_total_bytes", "gauge", "Partition size in bytes.")
+	for _, d := range snap.Disks {
+		p.sample("crunchyutils_disk_total_bytes", float64(d.Total), "mount", d.Mount)
+	}
+	p.family("crunchyutils_disk_used_bytes", "gauge", "Used partition space in bytes.")
+	for _, d := range snap.Disks {
+		p.sample("crunchyutils_disk_used_bytes", float64(d.Used), "mount", d.Mount)
+	}
+	p.family("crunchyutils_disk_used_percent", "gauge", "Used partition space in percent.")
+	for _, d := range snap.Disks {
+		p.sample("crunchyutils_disk_used_percent", d.UsedPercent, "mount", d.Mount)
+	}
+
+	// Network
+	p.family("crunchyutils_network_received_bytes_total", "counter", "Bytes received per interface.")
+	for _, n := range snap.Net {
+		p.sample("crunchyutils_network_received_bytes_total", float64(n.BytesRecv), "interface", n.Name)
+	}
+	p.family("crunchyutils_network_sent_bytes_total", "counter", "Bytes sent per interface.")
+	for _, n := range snap.Net {
+		p.sample("crunchyutils_network_sent_bytes_total", float64(n.BytesSent), "interface", n.Name)
+	}
+
+	// Top processes by CPU
+	procs := snap.Processes
+	if len(procs) > metricsTopProcs {
+		procs = procs[:metricsTopProcs]
+	}
+	p.family("crunchyutils_process_cpu_percent", "gauge", "CPU usage of the top processes in percent.")
+	for _, pr := range procs {
+		p.sample("crunchyutils_process_cpu_percent", pr.CPU, "pid", strconv.Itoa(int(pr.PID)), "name", pr.Name)
+	}
+	p.family("crunchyutils_process_memory_bytes", "gauge", "Resident memory of the top processes in bytes.")
+	for _, pr := range procs {
+		p.sample("crunchyutils_process_memory_bytes", float64(pr.Memory), "pid", strconv.Itoa(int(pr.PID)), "name", pr.Name)
+	}
+
+	p.family("crunchyutils_collected_timestamp_seconds", "gauge", "Unix time of the last collection.")
+	p.sample("crunchyutils_collected_timestamp_seconds", float64(snap.Time.UnixMilli())/1000)
+}
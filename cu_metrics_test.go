@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestWriteMetricsGolden(t *testing.T) {
+	snap := sysSnapshot{
+		Time:         time.Date(2025, 3, 10, 14, 0, 0, 500_000_000, time.UTC),
+		CPUPercent:   12.5,
+		CPUCores:     8,
+		RAMTotal:     16 << 30,
+		RAMUsed:      6 << 30,
+		RAMAvailable: 10 << 30,
+		Uptime:       3600,
+		Cgroup:       cgroupInfo{Version: 2, MemoryLimit: 2 << 30, MemoryUsed: 1 << 30, CPULimit: 1.5, PidsLimit: 512, PidsCurrent: 12},
+
+		CgroupCPUPercent: 40,
+		Disks: []diskInfo{
+			{Mount: "/", Total: 500e9, Used: 200e9, UsedPercent: 40},
+			{Mount: `C:\`, Total: 250e9, Used: 25e9, UsedPercent: 10},
+		},
+		Net: []netInfo{{Name: "eth0", BytesRecv: 1000, BytesSent: 2000}},
+		Processes: []procInfo{
+			{PID: 42, Name: `my "quoted" app`, CPU: 3.25, Memory: 1 << 20},
+			{PID: 7, Name: "multi\nline", CPU: 0, Memory: 4096},
+		},
+	}
+
+	var sb strings.Builder
+	writeMetrics(&sb, snap)
+	// The version changes with every release
+	got := strings.Replace(sb.String(), `version="`+CU_VERSION+`"`, `version="test"`, 1)
+
+	const golden = "testdata/metrics.prom"
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("metrics differ from %s (go test -run Golden -update rewrites it):\n%s", golden, got)
+	}
+}
+
+func TestPromEscape(t *testing.T) {
+	for in, want := range map[string]string{
+		`plain`:      `plain`,
+		`C:\`:        `C:\\`,
+		`say "hi"`:   `say \"hi\"`,
+		"two\nlines": `two\nlines`,
+		`\"` + "\n":  `\\\"\n`,
+	} {
+		if got := promEscape(in); got != want {
+			t.Errorf("%q: got %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,490 @@
+// #############################################
+// CrunchyUtils - System Monitor
+//
+// This file contains:
+// - Background collectors that each update one part
+//   of a shared snapshot on their own schedule
+// - The CrunchySystemMonitor screen
+//
+// The screen never waits for data: it redraws at a
+// fixed frame rate from whatever the collectors
+// published last. A slow collector (the CPU one
+// blocks for a second, the process walk can take
+// longer) only makes its own panel lag.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eiannone/keyboard"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+const (
+	monitorFrameRate  = 250 * time.Millisecond // redraw check interval
+	monitorSampleRate = 1 * time.Second        // alert checks & recording
+)
+
+// liveSnapshot is a sysSnapshot shared between collectors and readers
+type liveSnapshot struct {
+	mu   sync.RWMutex
+	snap sysSnapshot
+	gen  uint64 // bumped on every update, readers use it to skip redraws
+}
+
+// update applies fn to the snapshot under the lock
+func (l *liveSnapshot) update(fn func(*sysSnapshot)) {
+	l.mu.Lock()
+	fn(&l.snap)
+	l.snap.Time = time.Now()
+	l.gen++
+	l.mu.Unlock()
+}
+
+// get returns a copy of the snapshot and its generation.
+// Collectors replace slices instead of changing them, so sharing is safe.
+func (l *liveSnapshot) get() (sysSnapshot, uint64) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.snap, l.gen
+}
+
+// monitorCollector gathers one part of the snapshot.
+// collect does the slow work without holding the lock and returns
+// a small function that stores the result.
+type monitorCollector struct {
+	name     string
+	interval time.Duration // pause between runs, 0 = back to back
+	collect  func() func(*sysSnapshot)
+}
+
+// newMonitorCollectors returns a fresh set of collectors.
+// Built per call because the network collector keeps state.
+func newMonitorCollectors() []monitorCollector {
+	var (
+		lastNet     []netInfo
+		lastNetTime time.Time
+		lastCg      cgroupInfo
+		lastCgTime  time.Time
+	)
+
+	return []monitorCollector{
+		// cpu.Percent blocks for one second by itself
+		{"cpu", 0, func() func(*sysSnapshot) {
+			p := getCPUPercent()
+			return func(s *sysSnapshot) { s.CPUPercent = p }
+		}},
+		{"cores", time.Minute, func() func(*sysSnapshot) {
+			cores, _ := cpu.Counts(true)
+			return func(s *sysSnapshot) { s.CPUCores = cores }
+		}},
+		{"processes", 2 * time.Second, func() func(*sysSnapshot) {
+			all, err := getAllProcesses()
+			return func(s *sysSnapshot) {
+				if err == nil {
+					s.Processes = userProcesses(all)
+					s.CgroupUsage = cgroupBreakdown(all)
+				}
+			}
+		}},
+		{"memory", time.Second, func() func(*sysSnapshot) {
+			vm, err := mem.VirtualMemory()
+			return func(s *sysSnapshot) {
+				if err == nil {
+					s.RAMTotal, s.RAMUsed, s.RAMAvailable = vm.Total, vm.Used, vm.Available
+				}
+			}
+		}},
+		{"disks", 5 * time.Second, func() func(*sysSnapshot) {
+			disks := getDisks()
+			return func(s *sysSnapshot) { s.Disks = disks }
+		}},
+		{"network", time.Second, func() func(*sysSnapshot) {
+			cur := sysSnapshot{Time: time.Now(), Net: getNetCounters()}
+			recv, sent := cur.NetRates(sysSnapshot{Time: lastNetTime, Net: lastNet})
+			lastNet, lastNetTime = cur.Net, cur.Time
+			return func(s *sysSnapshot) {
+				s.Net = cur.Net
+				s.NetRecvRate, s.NetSentRate = recv, sent
+			}
+		}},
+		{"cgroup", time.Second, func() func(*sysSnapshot) {
+			cg, now := readCgroup(), time.Now()
+			cores, _ := cpu.Counts(true)
+			pct := cgroupCPUPercent(cg, lastCg, now.Sub(lastCgTime).Seconds(), cores)
+			lastCg, lastCgTime = cg, now
+			return func(s *sysSnapshot) { s.Cgroup, s.CgroupCPUPercent = cg, pct }
+		}},
+		{"uptime", 30 * time.Second, func() func(*sysSnapshot) {
+			up, _ := host.Uptime()
+			return func(s *sysSnapshot) { s.Uptime = up }
+		}},
+	}
+}
+
+// startCollectors runs all collectors until ctx is cancelled
+func startCollectors(ctx context.Context) *liveSnapshot {
+	live := &liveSnapshot{}
+	for _, c := range newMonitorCollectors() {
+		go func(c monitorCollector) {
+			for {
+				apply := c.collect()
+				if ctx.Err() != nil {
+					return
+				}
+				live.update(apply)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(c.interval):
+				}
+			}
+		}(c)
+	}
+	return live
+}
+
+// CrunchySystemMonitor shows live CPU, RAM, disk and network usage
+// and checks the alert rules every second
+func CrunchySystemMonitor() {
+	// Raw key presses: Enter/Q stops, L toggles the alert log,
+	// G the cgroup view, R recording
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		printError(fmt.Sprintf("Keyboard unavailable: %v", err))
+		return
+	}
+	defer keyboard.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	live := startCollectors(ctx)
+
+	var (
+		showLog bool
+		showCg  bool
+		rec     *monitorRecorder
+		status  string // last error, shown in the footer
+		drawn   uint64 // generation on screen
+		redraw  = true
+	)
+	// Always finish the recording, the gzip footer is needed
+	defer func() {
+		if rec != nil {
+			rec.Close()
+			printInfo("Recording saved: " + rec.path)
+		}
+	}()
+
+	scr := newScreen()
+	defer scr.Close()
+
+	frames := time.NewTicker(monitorFrameRate)
+	defer frames.Stop()
+	samples := time.NewTicker(monitorSampleRate)
+	defer samples.Stop()
+
+	for {
+		select {
+		case ev := <-keys:
+			switch {
+			case isStopKey(ev):
+				scr.Close()
+				printInfo("System Monitor stopped") // user pressed Enter
+				return
+			case ev.Rune == 'l', ev.Rune == 'L':
+				showLog, showCg = !showLog, false
+			case ev.Rune == 'g', ev.Rune == 'G':
+				showCg, showLog = !showCg, false
+			case ev.Rune == 'r', ev.Rune == 'R':
+				status = ""
+				if rec != nil {
+					rec.Close()
+					status = "Saved " + rec.path
+					rec = nil
+					break
+				}
+				if rec, err = newMonitorRecorder(""); err != nil {
+					status = RED + "Recording failed: " + err.Error()
+					rec = nil
+				}
+			}
+			redraw = true
+
+		case <-termResized:
+			redraw = true
+
+		case <-samples.C:
+			// Fixed rate sampling for alerts and recording
+			snap, _ := live.get()
+			snap.Time = time.Now()
+			checkAlerts(snap)
+
+			if rec != nil {
+				if err := rec.Write(snap); err != nil {
+					status = RED + "Recording failed: " + err.Error()
+					rec.Close()
+					rec = nil
+					redraw = true
+				}
+			}
+
+		case <-frames.C:
+			snap, gen := live.get()
+			if gen == drawn && !redraw {
+				continue
+			}
+			drawn, redraw = gen, false
+
+			fprintCommandTitle(scr, "CrunchyUtils")
+			fprintCommandTitle(scr, "System Monitor")
+			fline(scr)
+
+			if showLog {
+				showAlertLog(scr)
+				fmt.Fprintf(scr, "Press [L] to go back, [Enter] to exit\n")
+			} else if showCg {
+				_, rows := termSize()
+				for _, l := range monitorCgroupPanel(snap, rows-6) {
+					fmt.Fprintln(scr, l)
+				}
+				fline(scr)
+				fmt.Fprintf(scr, "Press [G] to go back, [Enter] to exit\n")
+			} else {
+				renderMonitor(scr, snap)
+				footer := "[R] record"
+				if rec != nil {
+					footer = RED + "● REC " + RC + rec.path
+				} else if status != "" {
+					footer = status
+				}
+				fmt.Fprintf(scr, "Press [Enter] to exit, [L] alert log, [G] cgroups, %s\n", footer)
+			}
+			scr.Flush()
+		}
+	}
+}
+
+// createBar turns a percentage like "58%" into a visual bar
+func createBar(value string) string {
+	value = strings.TrimSuffix(value, "%") // remove % sign
+	percent, err := strconv.Atoi(value)    // parse number
+	if err != nil {
+		return "[??????????]" // fallback if parsing fails
+	}
+	totalBars := 10
+	filled := percent * totalBars / 100 // scale to 10 bars
+	if filled > totalBars {
+		filled = totalBars
+	}
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", totalBars-filled) + "]" // bar string
+}
+
+// renderMonitor writes the monitor panels for one snapshot to w.
+// The panels are stacked like on a COLS x LINES terminal. Wide terminals
+// get two columns, tall ones more processes, small ones fewer panels.
+func renderMonitor(w io.Writer, snap sysSnapshot) {
+	cols, rows := termSize()
+
+	// Two columns: CPU + processes left, RAM, disks & network right
+	if cols >= 2*monitorColWidth+3 {
+		colWidth := (cols - 3) / 2
+		procRows := max(rows-10, 5) // title, header, footer & lines
+		right := monitorRAMPanel(snap)
+		right = append(right, sepLine(YELLOW, colWidth))
+		right = append(right, monitorDiskPanel(snap, len(snap.Disks))...)
+		right = append(right, sepLine(YELLOW, colWidth))
+		right = append(right, monitorNetPanel(snap)...)
+		writeColumns(w, monitorCPUPanel(snap, procRows), right, colWidth)
+		fline(w)
+		return
+	}
+
+	// Stacked: 22 fixed rows, the rest is process list
+	procRows := rows - 22
+	showNet := true
+	if procRows < 3 {
+		// Network panel makes room for at least a few processes
+		showNet = false
+		procRows += 4
+	}
+	procRows = max(procRows, 1)
+
+	panels := [][]string{
+		monitorCPUPanel(snap, procRows),
+		monitorRAMPanel(snap),
+		monitorDiskPanel(snap, 1),
+	}
+	if showNet {
+		panels = append(panels, monitorNetPanel(snap))
+	}
+	for _, p := range panels {
+		for _, l := range p {
+			fmt.Fprintln(w, l)
+		}
+		fline(w)
+	}
+}
+
+// monitorColWidth is the minimum width of one monitor column
+const monitorColWidth = 45
+
+// writeColumns prints two panels side by side
+func writeColumns(w io.Writer, left, right []string, width int) {
+	for i := 0; i < max(len(left), len(right)); i++ {
+		var l, r string
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		fmt.Fprintf(w, "%s %s│%s %s\n", padVisible(truncateVisible(l, width), width), YELLOW, RC, r)
+	}
+}
+
+// monitorCPUPanel shows CPU usage and the busiest processes
+func monitorCPUPanel(snap sysSnapshot, procRows int) []string {
+	cpuUsage := fmt.Sprintf("%.0f%%", snap.EffectiveCPUPercent())
+	cores := strconv.Itoa(snap.CPUCores)
+	if snap.CPULimited() {
+		cores += fmt.Sprintf(" (limit %.2f)", snap.Cgroup.CPULimit)
+	}
+	p := []string{
+		fmt.Sprintf("%s# CPU Info:%s", YELLOW, RC),
+		fmt.Sprintf("└┬CPU Cores: %s", cores),
+		fmt.Sprintf(" └Usage    : %s %s", cpuUsage, createBar(cpuUsage)),
+		fmt.Sprintf(" %s# Top CPU Tasks:%s", YELLOW, RC),
+	}
+
+	top := topProcesses(snap.Processes, procRows)
+	for i := 0; i < procRows; i++ {
+		prefix := "├"
+		if i == procRows-1 { // last item gets └
+			prefix = "└"
+		}
+		if i >= len(top) {
+			p = append(p, fmt.Sprintf(" %s...", prefix))
+			continue
+		}
+		p = append(p, fmt.Sprintf(" %s%-18s %5.1f%% %9s", prefix, top[i].Name, top[i].CPU, formatBytes(float64(top[i].Memory))))
+	}
+	return p
+}
+
+// monitorRAMPanel shows RAM size and usage
+func monitorRAMPanel(snap sysSnapshot) []string {
+	ramUsage := fmt.Sprintf("%.0f%%", snap.RAMPercent())
+	total := fmt.Sprintf("%.0f MB", float64(snap.RAMTotal)/1024/1024)
+	if snap.MemoryLimited() {
+		total += fmt.Sprintf(" (limit %.0f MB)", float64(snap.Cgroup.MemoryLimit)/1024/1024)
+	}
+	return []string{
+		fmt.Sprintf("%s# RAM Info:%s", YELLOW, RC),
+		fmt.Sprintf("└┬Total RAM: %s", total),
+		fmt.Sprintf(" └Usage    : %s %s", ramUsage, createBar(ramUsage)),
+	}
+}
+
+// monitorDiskPanel shows the first partition in detail,
+// or one line per partition if more than one is wanted
+func monitorDiskPanel(snap sysSnapshot, disks int) []string {
+	p := []string{fmt.Sprintf("%s# Disk Info:%s", YELLOW, RC)}
+
+	if disks <= 1 || len(snap.Disks) <= 1 {
+		diskName, diskTotal, diskUsed := "Unknown", "0 GB", "0%"
+		if len(snap.Disks) > 0 {
+			d := snap.Disks[0]
+			diskName = d.Mount
+			diskTotal = fmt.Sprintf("%.0f GB", float64(d.Total)/1024/1024/1024)
+			diskUsed = fmt.Sprintf("%.0f%%", d.UsedPercent)
+		}
+		return append(p,
+			fmt.Sprintf("└┬Disk Name : %s", diskName),
+			fmt.Sprintf(" ├Total     : %s", diskTotal),
+			fmt.Sprintf(" └Used      : %s %s", diskUsed, createBar(diskUsed)),
+		)
+	}
+
+	list := snap.Disks[:min(disks, len(snap.Disks))]
+	for i, d := range list {
+		prefix := "├"
+		if i == len(list)-1 {
+			prefix = "└"
+		}
+		used := fmt.Sprintf("%.0f%%", d.UsedPercent)
+		mount := d.Mount
+		if len(mount) > 12 {
+			mount = "..." + mount[len(mount)-9:]
+		}
+		p = append(p, fmt.Sprintf("%s%-12s %4s %s %6.0f GB", prefix, mount, used, createBar(used), float64(d.Total)/1024/1024/1024))
+	}
+	return p
+}
+
+// monitorNetPanel shows the current network throughput
+func monitorNetPanel(snap sysSnapshot) []string {
+	return []string{
+		fmt.Sprintf("%s# Network:%s", YELLOW, RC),
+		fmt.Sprintf("└┬Download  : %s/s", formatBytes(snap.NetRecvRate)),
+		fmt.Sprintf(" └Upload    : %s/s", formatBytes(snap.NetSentRate)),
+	}
+}
+
+// monitorCgroupPanel compares host and cgroup limits and lists
+// the usage per cgroup / systemd unit in the remaining rows
+func monitorCgroupPanel(snap sysSnapshot, rows int) []string {
+	cg := snap.Cgroup
+	if cg.Version == 0 {
+		return []string{fmt.Sprintf("%s# Cgroups:%s not available on this system", YELLOW, RC)}
+	}
+
+	limit := func(set bool, format string, v any) string {
+		if !set {
+			return "none"
+		}
+		return fmt.Sprintf(format, v)
+	}
+	p := []string{
+		fmt.Sprintf("%s# Cgroup v%d:%s %s", YELLOW, cg.Version, RC, cg.Path),
+		fmt.Sprintf("└┬%-7s %-12s %-12s %s", "", "Host", "Limit", "Used"),
+		fmt.Sprintf(" ├%-7s %-12s %-12s %s", "Memory", formatBytes(float64(snap.RAMTotal)),
+			limit(cg.MemoryLimit > 0, "%s", formatBytes(float64(cg.MemoryLimit))), formatBytes(float64(cg.MemoryUsed))),
+		fmt.Sprintf(" ├%-7s %-12s %-12s %.0f%%", "CPU", fmt.Sprintf("%d cores", snap.CPUCores),
+			limit(cg.CPULimit > 0, "%.2f cores", cg.CPULimit), snap.CgroupCPUPercent),
+		fmt.Sprintf(" └%-7s %-12s %-12s %d", "PIDs", "-", limit(cg.PidsLimit > 0, "%d", cg.PidsLimit), cg.PidsCurrent),
+		fmt.Sprintf("%s# Usage per cgroup:%s", YELLOW, RC),
+	}
+
+	// Recordings from before the breakdown was stored only have user processes
+	groups := snap.CgroupUsage
+	if groups == nil {
+		groups = cgroupBreakdown(snap.Processes)
+	}
+	n := min(len(groups), max(rows-len(p), 1))
+	for i, g := range groups[:n] {
+		prefix := "├"
+		if i == n-1 {
+			prefix = "└"
+		}
+		unit := cgroupUnit(g.Group)
+		if len(unit) > 30 {
+			unit = unit[:27] + "..."
+		}
+		p = append(p, fmt.Sprintf("%s%-30s %4d procs %5.1f%% %9s", prefix, unit, g.Procs, g.CPU, formatBytes(float64(g.Memory))))
+	}
+	return p
+}
@@ -0,0 +1,359 @@
+// #############################################
+// CrunchyUtils - Notification Backends
+//
+// This file contains:
+// - The notifier backends: terminal bell, desktop,
+//   webhook (JSON POST), ntfy, Gotify, SMTP mail
+//   and a log file
+// - Sending one notification to all backends of a tool
+// - The notify subcommand to try the setup
+//
+// Backends are named in "notify.backends" of the config,
+// every tool lists the ones it uses in "backends" (else
+// "notify.default" applies). Example:
+//
+//   "backends": {
+//     "desktop": {"type": "desktop"},
+//     "phone":   {"type": "ntfy", "url": "https://ntfy.sh/my-topic"},
+//     "mail":    {"type": "smtp", "host": "localhost:25",
+//                 "from": "cu@host", "to": ["me@host"]}
+//   },
+//   "tools": {"alert": {"backends": ["desktop", "phone", "mail"]}}
+//
+// Try it with:
+//   crunchyutils notify -tool alert -urgency critical "Disk full"
+//
+// Failed sends are written to notify-errors.log next to
+// the config, the TUI has no place to show them.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gen2brain/beeep"
+)
+
+// notifier is one way to reach the user
+type notifier interface {
+	Send(n notification) error
+}
+
+// notifierConfig is one entry of notify.backends.
+// Which fields are used depends on Type.
+type notifierConfig struct {
+	Type     string            `json:"type"`              // bell, desktop, webhook, ntfy, gotify, smtp, log
+	URL      string            `json:"url,omitempty"`     // webhook target, ntfy topic or Gotify server
+	Token    string            `json:"token,omitempty"`   // ntfy access token or Gotify app token
+	Headers  map[string]string `json:"headers,omitempty"` // extra webhook headers
+	Host     string            `json:"host,omitempty"`    // SMTP server as host:port
+	Username string            `json:"username,omitempty"`
+	Password string            `json:"password,omitempty"`
+	From     string            `json:"from,omitempty"`
+	To       []string          `json:"to,omitempty"`
+	Path     string            `json:"path,omitempty"` // log file, default notifications.log
+}
+
+// notifyClient is used by all HTTP backends
+var notifyClient = &http.Client{Timeout: 10 * time.Second}
+
+// newNotifier builds the backend described by c
+func newNotifier(c notifierConfig) (notifier, error) {
+	switch c.Type {
+	case "bell":
+		return bellNotifier{w: os.Stdout}, nil
+	case "desktop":
+		return desktopNotifier{}, nil
+	case "webhook", "ntfy", "gotify":
+		if c.URL == "" {
+			return nil, fmt.Errorf("%s needs a url", c.Type)
+		}
+		switch c.Type {
+		case "ntfy":
+			return ntfyNotifier{url: c.URL, token: c.Token, client: notifyClient}, nil
+		case "gotify":
+			return gotifyNotifier{url: c.URL, token: c.Token, client: notifyClient}, nil
+		}
+		return webhookNotifier{url: c.URL, headers: c.Headers, client: notifyClient}, nil
+	case "smtp":
+		if c.Host == "" || c.From == "" || len(c.To) == 0 {
+			return nil, errors.New("smtp needs host, from and to")
+		}
+		return smtpNotifier{host: c.Host, username: c.Username, password: c.Password, from: c.From, to: c.To}, nil
+	case "log":
+		path := c.Path
+		if path == "" {
+			path = dataPath("notifications.log")
+		}
+		return logNotifier{path: path}, nil
+	}
+	return nil, fmt.Errorf("unknown backend type %q", c.Type)
+}
+
+// bellNotifier rings the terminal bell
+type bellNotifier struct {
+	w io.Writer
+}
+
+func (b bellNotifier) Send(n notification) error {
+	_, err := io.WriteString(b.w, "\a")
+	return err
+}
+
+// desktopNotifier shows a desktop notification through beeep
+type desktopNotifier struct{}
+
+func (desktopNotifier) Send(n notification) error {
+	// beeep knows two levels, critical ones stay on screen
+	if n.Urgency == urgencyCritical {
+		return beeep.Alert(n.Title, n.Body, n.Icon)
+	}
+	return beeep.Notify(n.Title, n.Body, n.Icon)
+}
+
+// webhookPayload is the JSON body of a webhook POST
+type webhookPayload struct {
+	Tool    string    `json:"tool"`
+	Title   string    `json:"title"`
+	Body    string    `json:"body"`
+	Urgency string    `json:"urgency"`
+	Host    string    `json:"host"`
+	Time    time.Time `json:"time"`
+}
+
+// webhookNotifier POSTs the notification as JSON
+type webhookNotifier struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (w webhookNotifier) Send(n notification) error {
+	host, _ := os.Hostname()
+	data, err := json.Marshal(webhookPayload{n.Tool, n.Title, n.Body, urgencyOrNormal(n.Urgency), host, n.Time})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	return doNotifyRequest(w.client, req)
+}
+
+// ntfyNotifier publishes to an ntfy topic URL, the body is the message
+type ntfyNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// ntfyPriority maps urgencies to ntfy priorities (1-5)
+var ntfyPriority = map[string]string{urgencyLow: "2", urgencyNormal: "3", urgencyCritical: "5"}
+
+func (t ntfyNotifier) Send(n notification) error {
+	req, err := http.NewRequest(http.MethodPost, t.url, strings.NewReader(n.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", n.Title)
+	req.Header.Set("Priority", ntfyPriority[urgencyOrNormal(n.Urgency)])
+	if n.Tool != "" {
+		req.Header.Set("Tags", n.Tool)
+	}
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	return doNotifyRequest(t.client, req)
+}
+
+// gotifyNotifier posts to the /message endpoint of a Gotify server
+type gotifyNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// gotifyPriority maps urgencies to Gotify priorities (0-10)
+var gotifyPriority = map[string]int{urgencyLow: 2, urgencyNormal: 5, urgencyCritical: 8}
+
+func (g gotifyNotifier) Send(n notification) error {
+	data, err := json.Marshal(map[string]any{
+		"title":    n.Title,
+		"message":  n.Body,
+		"priority": gotifyPriority[urgencyOrNormal(n.Urgency)],
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(g.url, "/")+"/message", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.token)
+	return doNotifyRequest(g.client, req)
+}
+
+// urgencyOrNormal returns u, or normal if u is empty or unknown
+func urgencyOrNormal(u string) string {
+	switch u {
+	case urgencyLow, urgencyCritical:
+		return u
+	}
+	return urgencyNormal
+}
+
+// doNotifyRequest sends req and turns non-2xx answers into errors
+func doNotifyRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) // lets the connection be reused
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+// smtpNotifier sends a plain text mail
+type smtpNotifier struct {
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func (m smtpNotifier) Send(n notification) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		hostname, _, err := net.SplitHostPort(m.host)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.username, m.password, hostname)
+	}
+	return smtp.SendMail(m.host, auth, m.from, m.to, m.message(n))
+}
+
+// message builds the mail with headers, lines end in CRLF
+func (m smtpNotifier) message(n notification) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.to, ", "))
+	fmt.Fprintf(&b, "Subject: [CrunchyUtils] %s\r\n", n.Title)
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	if n.Urgency == urgencyCritical {
+		b.WriteString("X-Priority: 1\r\n")
+	}
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(n.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// logNotifier appends one line per notification to a file
+type logNotifier struct {
+	path string
+}
+
+func (l logNotifier) Send(n notification) error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s [%s] %s %s: %s\n", n.Time.Format("2006-01-02 15:04:05"), n.Tool, urgencyOrNormal(n.Urgency), n.Title, n.Body)
+	return err
+}
+
+// sendToBackends sends n to all named backends at once.
+// The result joins the errors of the backends that failed.
+func sendToBackends(names []string, n notification) error {
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, ok := config.Notify.Backends[name]
+			if !ok {
+				errs[i] = fmt.Errorf("%s: no such backend", name)
+				return
+			}
+			b, err := newNotifier(c)
+			if err == nil {
+				err = b.Send(n)
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %v", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// logNotifyError records failed sends, best effort
+func logNotifyError(n notification, err error) {
+	f, ferr := os.OpenFile(dataPath("notify-errors.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if ferr != nil {
+		return
+	}
+	defer f.Close()
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintf(f, "%s [%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), n.Tool, line)
+	}
+}
+
+// notifyCmd runs the notify subcommand: sends a message through the
+// backends of a tool and reports what failed
+func notifyCmd(args []string) int {
+	fs := flag.NewFlagSet("notify", flag.ContinueOnError)
+	tool := fs.String("tool", "alert", "Send with the settings of this tool")
+	title := fs.String("title", "CrunchyUtils test", "Notification title")
+	urgency := fs.String("urgency", urgencyNormal, "low, normal or critical")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	body := strings.Join(fs.Args(), " ")
+	if body == "" {
+		body = "Test notification from CrunchyUtils " + CU_VERSION
+	}
+
+	n := notification{Tool: *tool, Title: *title, Body: body, Urgency: *urgency}
+	if err := sendNotification(n); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			eprintError(line)
+		}
+		return 1
+	}
+	printSuccess("Sent to " + strings.Join(toolBackends(*tool), ", "))
+	return 0
+}
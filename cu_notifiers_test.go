@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNotification = notification{
+	Tool:    "alert",
+	Title:   "High CPU",
+	Body:    "CPU above 90% for 30s",
+	Urgency: urgencyCritical,
+	Time:    time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC),
+}
+
+// recordServer answers 200 and hands every request with its body to the test
+func recordServer(t *testing.T) (*httptest.Server, chan *http.Request, chan string) {
+	t.Helper()
+	reqs, bodies := make(chan *http.Request, 4), make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		reqs <- r
+		bodies <- string(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, reqs, bodies
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	srv, reqs, bodies := recordServer(t)
+	b, err := newNotifier(notifierConfig{Type: "webhook", URL: srv.URL + "/hook", Headers: map[string]string{"X-Secret": "s3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Send(testNotification); err != nil {
+		t.Fatal(err)
+	}
+
+	r, body := <-reqs, <-bodies
+	if r.Method != http.MethodPost || r.URL.Path != "/hook" {
+		t.Errorf("got %s %s, want POST /hook", r.Method, r.URL.Path)
+	}
+	if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Secret") != "s3" {
+		t.Errorf("unexpected headers %v", r.Header)
+	}
+	var p webhookPayload
+	if err := json.Unmarshal([]byte(body), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Tool != "alert" || p.Title != "High CPU" || p.Body != testNotification.Body || p.Urgency != urgencyCritical || !p.Time.Equal(testNotification.Time) {
+		t.Errorf("got payload %+v", p)
+	}
+}
+
+func TestWebhookNotifierError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	b, _ := newNotifier(notifierConfig{Type: "webhook", URL: srv.URL})
+	if err := b.Send(testNotification); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("got %v, want a 500 error", err)
+	}
+}
+
+func TestNtfyNotifier(t *testing.T) {
+	srv, reqs, bodies := recordServer(t)
+	b, _ := newNotifier(notifierConfig{Type: "ntfy", URL: srv.URL + "/cu-alerts", Token: "tk"})
+	if err := b.Send(testNotification); err != nil {
+		t.Fatal(err)
+	}
+
+	r, body := <-reqs, <-bodies
+	if r.URL.Path != "/cu-alerts" || body != testNotification.Body {
+		t.Errorf("got %s with %q", r.URL.Path, body)
+	}
+	for k, want := range map[string]string{"Title": "High CPU", "Priority": "5", "Tags": "alert", "Authorization": "Bearer tk"} {
+		if got := r.Header.Get(k); got != want {
+			t.Errorf("header %s: got %q, want %q", k, got, want)
+		}
+	}
+}
+
+func TestGotifyNotifier(t *testing.T) {
+	srv, reqs, bodies := recordServer(t)
+	b, _ := newNotifier(notifierConfig{Type: "gotify", URL: srv.URL + "/", Token: "app"})
+	n := testNotification
+	n.Urgency = ""
+	if err := b.Send(n); err != nil {
+		t.Fatal(err)
+	}
+
+	r, body := <-reqs, <-bodies
+	if r.URL.Path != "/message" || r.Header.Get("X-Gotify-Key") != "app" {
+		t.Errorf("got %s, key %q", r.URL.Path, r.Header.Get("X-Gotify-Key"))
+	}
+	var msg struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}
+	if err := json.Unmarshal([]byte(body), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Title != "High CPU" || msg.Message != n.Body || msg.Priority != 5 {
+		t.Errorf("got %+v", msg)
+	}
+}
+
+// smtpStandIn accepts one SMTP session on localhost and hands
+// the recipients and the mail data to the test
+func smtpStandIn(t *testing.T) (string, chan []string, chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	rcpts, mails := make(chan []string, 1), make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost stand-in")
+
+		var to []string
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.Fields(line + " x")[0])
+			switch cmd {
+			case "EHLO", "HELO", "MAIL":
+				tp.PrintfLine("250 OK")
+			case "RCPT":
+				to = append(to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, _ := tp.ReadDotBytes()
+				rcpts <- to
+				mails <- string(data)
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), rcpts, mails
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, rcpts, mails := smtpStandIn(t)
+	b, err := newNotifier(notifierConfig{Type: "smtp", Host: addr, From: "cu@example.org", To: []string{"ops@example.org", "me@example.org"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Send(testNotification); err != nil {
+		t.Fatal(err)
+	}
+
+	if to := <-rcpts; strings.Join(to, ",") != "ops@example.org,me@example.org" {
+		t.Errorf("got recipients %v", to)
+	}
+	mail := <-mails
+	for _, want := range []string{"Subject: [CrunchyUtils] High CPU\n", "To: ops@example.org, me@example.org\n", "X-Priority: 1\n", "\n\nCPU above 90% for 30s\n"} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("mail lacks %q:\n%s", want, mail)
+		}
+	}
+}
+
+func TestNewNotifierErrors(t *testing.T) {
+	for _, c := range []notifierConfig{
+		{Type: "webhook"},
+		{Type: "smtp", Host: "localhost:25"},
+		{Type: "pigeon"},
+	} {
+		if _, err := newNotifier(c); err == nil {
+			t.Errorf("%+v: want an error", c)
+		}
+	}
+}
+
+func TestSendToBackends(t *testing.T) {
+	srv, reqs, _ := recordServer(t)
+	logPath := filepath.Join(t.TempDir(), "notify.log")
+
+	saved := config
+	defer func() { config = saved }()
+	config.Notify.Backends = map[string]notifierConfig{
+		"hook": {Type: "webhook", URL: srv.URL},
+		"file": {Type: "log", Path: logPath},
+	}
+
+	err := sendToBackends([]string{"hook", "file", "missing"}, testNotification)
+	if err == nil || !strings.Contains(err.Error(), "missing: no such backend") {
+		t.Errorf("got %v, want an error for the missing backend", err)
+	}
+
+	// The other backends still got it
+	select {
+	case <-reqs:
+	default:
+		t.Error("webhook was not called")
+	}
+	f, err := os.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Scan()
+	if got, want := sc.Text(), "2025-03-10 14:00:00 [alert] critical High CPU: CPU above 90% for 30s"; got != want {
+		t.Errorf("got log line %q, want %q", got, want)
+	}
+}
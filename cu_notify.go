@@ -0,0 +1,194 @@
+// #############################################
+// CrunchyUtils - Notifications
+//
+// This file contains:
+// - notifyAlarm: notification to the tool's backends
+//   (cu_notifiers.go) plus a melody
+// - Built-in and user-defined beep melodies
+// - Per-tool settings: melody, urgency, icon, mute,
+//   backends and repeat until acknowledged
+//
+// Callers describe what happened (tool, title, body),
+// the "notify" section of the config decides how it
+// sounds. A tool set to repeat rings like an alarm
+// until [A] is pressed in the main menu.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/gen2brain/beeep"
+)
+
+// Urgency levels, critical notifications stay on screen
+const (
+	urgencyLow      = "low"
+	urgencyNormal   = "normal"
+	urgencyCritical = "critical"
+)
+
+// notification is one event to tell the user about
+type notification struct {
+	Tool    string // key in notify.tools, e.g. "timer"
+	Title   string // "" = CrunchyUtils
+	Body    string
+	Urgency string    // low, normal or critical, "" = normal
+	Icon    string    // icon name or path to a png
+	Time    time.Time // zero = when it is sent
+}
+
+// beepTone is one note of a melody, Freq 0 is a rest
+type beepTone struct {
+	Freq float64 `json:"freq"`
+	Ms   int     `json:"ms"`
+}
+
+// toolNotifyConfig are the notification settings of one tool.
+// Empty values keep what the tool asks for.
+type toolNotifyConfig struct {
+	Melody   string   `json:"melody,omitempty"`
+	Urgency  string   `json:"urgency,omitempty"`
+	Icon     string   `json:"icon,omitempty"`
+	Mute     bool     `json:"mute,omitempty"`             // no sound, the notification still shows
+	Repeat   bool     `json:"repeat_until_ack,omitempty"` // ring like an alarm until acknowledged
+	Backends []string `json:"backends,omitempty"`         // names in notify.backends, empty = notify.default
+}
+
+// notifyConfig is the "notify" section of the config file
+type notifyConfig struct {
+	Melodies map[string][]beepTone     `json:"melodies"` // own melodies, a built-in name replaces it
+	Backends map[string]notifierConfig `json:"backends"`
+	Default  []string                  `json:"default"` // backends of tools that list none
+	Tools    toolNotifyConfigs         `json:"tools"`
+}
+
+// toolNotifyConfigs are the per-tool settings by tool name
+type toolNotifyConfigs map[string]toolNotifyConfig
+
+// UnmarshalJSON merges every entry field by field into the one
+// already there, so {"alarm": {"mute": true}} keeps the default
+// melody and urgency of the alarm
+func (m *toolNotifyConfigs) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	merged := make(toolNotifyConfigs, len(*m)+len(raw))
+	maps.Copy(merged, *m)
+	for name, r := range raw {
+		tc := merged[name]
+		if err := json.Unmarshal(r, &tc); err != nil {
+			return fmt.Errorf("notify.tools.%s: %v", name, err)
+		}
+		merged[name] = tc
+	}
+	*m = merged
+	return nil
+}
+
+func defaultNotifyConfig() notifyConfig {
+	return notifyConfig{
+		Melodies: map[string][]beepTone{},
+		Backends: map[string]notifierConfig{
+			"desktop": {Type: "desktop"},
+			"bell":    {Type: "bell"},
+		},
+		Default: []string{"desktop"},
+		Tools: toolNotifyConfigs{
+			"timer":    {Melody: "chime"},
+			"pomodoro": {Melody: "chime"},
+			"alarm":    {Melody: "alarm", Urgency: urgencyCritical},
+			"cleanup":  {Melody: "success"},
+			"alert":    {Melody: "warning", Urgency: urgencyCritical},
+			"power":    {Melody: "warning", Urgency: urgencyCritical},
+		},
+	}
+}
+
+// builtinMelodies can be picked by name in notify.tools
+var builtinMelodies = map[string][]beepTone{
+	"default": {{600, 150}, {0, 100}, {800, 200}, {0, 100}, {1000, 300}},
+	"chime":   {{1047, 150}, {0, 50}, {784, 150}, {0, 50}, {523, 300}},
+	"success": {{523, 120}, {0, 40}, {659, 120}, {0, 40}, {784, 250}},
+	"warning": {{440, 300}, {0, 100}, {330, 400}},
+	"alarm":   {{880, 200}, {0, 100}, {880, 200}, {0, 100}, {880, 200}, {0, 100}, {880, 200}},
+	"none":    {},
+}
+
+// melody returns the tones for name, config melodies first.
+// Unknown names fall back to the default melody.
+func melody(name string) []beepTone {
+	if tones, ok := config.Notify.Melodies[name]; ok {
+		return tones
+	}
+	if tones, ok := builtinMelodies[name]; ok {
+		return tones
+	}
+	return builtinMelodies["default"]
+}
+
+// playMelody beeps the melody name, blocks until it is done
+func playMelody(name string) {
+	for _, t := range melody(name) {
+		if t.Freq <= 0 {
+			time.Sleep(time.Duration(t.Ms) * time.Millisecond)
+			continue
+		}
+		beeep.Beep(t.Freq, t.Ms)
+	}
+}
+
+// notifyAlarm sends n to the tool's backends and plays its melody.
+// Tools set to repeat ring until acknowledged instead.
+func notifyAlarm(n notification) {
+	if config.Notify.Tools[n.Tool].Repeat {
+		ringNotification(n)
+		return
+	}
+	sendNotification(n)
+}
+
+// toolBackends returns the backend names a tool sends to
+func toolBackends(tool string) []string {
+	if names := config.Notify.Tools[tool].Backends; len(names) > 0 {
+		return names
+	}
+	return config.Notify.Default
+}
+
+// sendNotification sends n once with the tool's settings applied.
+// Failures are logged and returned.
+func sendNotification(n notification) error {
+	tc := config.Notify.Tools[n.Tool]
+	if n.Title == "" {
+		n.Title = "CrunchyUtils"
+	}
+	if tc.Urgency != "" {
+		n.Urgency = tc.Urgency
+	}
+	if tc.Icon != "" {
+		n.Icon = tc.Icon
+	}
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+
+	// The melody plays while the backends are busy
+	done := make(chan error, 1)
+	go func() { done <- sendToBackends(toolBackends(n.Tool), n) }()
+	if !tc.Mute {
+		playMelody(tc.Melody)
+	}
+	err := <-done
+	if err != nil {
+		logNotifyError(n, err)
+	}
+	return err
+}
@@ -0,0 +1,268 @@
+// #############################################
+// CrunchyUtils - Pomodoro
+//
+// This file contains:
+// - Pomodoro focus sessions: work, short and long
+//   breaks, built on runCountdown and notifyAlarm
+// - The session log (pomodoro.jsonl next to the
+//   config) with the daily tally
+// - The weekly summary
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eiannone/keyboard"
+)
+
+// pomodoroConfig is the "pomodoro" section of the config file
+type pomodoroConfig struct {
+	WorkMinutes       int `json:"work_minutes"`
+	ShortBreakMinutes int `json:"short_break_minutes"`
+	LongBreakMinutes  int `json:"long_break_minutes"`
+	Cycles            int `json:"cycles"` // work sessions before the long break
+}
+
+func defaultPomodoroConfig() pomodoroConfig {
+	return pomodoroConfig{WorkMinutes: 25, ShortBreakMinutes: 5, LongBreakMinutes: 15, Cycles: 4}
+}
+
+// pomodoroSession is one finished work session in the log
+type pomodoroSession struct {
+	Time    time.Time `json:"time"` // when it ended
+	Label   string    `json:"label,omitempty"`
+	Minutes int       `json:"minutes"`
+}
+
+// pomodoroLogPath is where finished sessions are appended
+func pomodoroLogPath() string {
+	return dataPath("pomodoro.jsonl")
+}
+
+// logPomodoro appends a finished session to the log
+func logPomodoro(s pomodoroSession) error {
+	path := pomodoroLogPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(s)
+}
+
+// loadPomodoros reads all sessions ended at or after since.
+// Broken lines are skipped, a missing log is no error.
+func loadPomodoros(since time.Time) ([]pomodoroSession, error) {
+	f, err := os.Open(pomodoroLogPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var list []pomodoroSession
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var s pomodoroSession
+		if json.Unmarshal(sc.Bytes(), &s) != nil || s.Time.Before(since) {
+			continue
+		}
+		list = append(list, s)
+	}
+	return list, sc.Err()
+}
+
+// startOfDay returns midnight of t's day
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// pomodoroToday returns the number of sessions and minutes of today
+func pomodoroToday() (int, int) {
+	list, _ := loadPomodoros(startOfDay(time.Now()))
+	minutes := 0
+	for _, s := range list {
+		minutes += s.Minutes
+	}
+	return len(list), minutes
+}
+
+// pomodoroMenu is the Pomodoro entry of the Timer menu
+func pomodoroMenu() {
+	for {
+		pc := config.Pomodoro
+		count, minutes := pomodoroToday()
+
+		printCommandTitle("Pomodoro")
+		fmt.Printf(" Today: %s%d sessions%s (%d min)\n", GREEN, count, RC, minutes)
+		fmt.Printf(" Work %d min, short break %d min, long break %d min after %d sessions\n",
+			pc.WorkMinutes, pc.ShortBreakMinutes, pc.LongBreakMinutes, pc.Cycles)
+		line()
+		fmt.Printf(" [0] - %sReturn%s\n", RED, RC)
+		fmt.Printf(" [1] - %sStart focus session%s\n", YELLOW, RC)
+		fmt.Printf(" [2] - %sWeekly summary%s\n", YELLOW, RC)
+		fmt.Printf(" [3] - %sSettings%s\n", YELLOW, RC)
+		line()
+		fmt.Printf("Press key (0-3)\n")
+
+		c, _, err := keyboard.GetSingleKey()
+		if err != nil {
+			continue
+		}
+
+		switch c {
+		case '0', 27: // Return or ESC
+			return
+		case '1':
+			fmt.Printf("Label (optional)%s", PROMPT)
+			label, _ := reader.ReadString('\n')
+			runPomodoro(strings.TrimSpace(label))
+		case '2':
+			pomodoroSummary()
+		case '3':
+			pomodoroSettings()
+		default:
+			fmt.Printf("\nInvalid Option\n")
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		pause()
+	}
+}
+
+// runPomodoro runs work sessions and breaks until all cycles are
+// done or a countdown is cancelled
+func runPomodoro(label string) {
+	pc := config.Pomodoro
+	cycles := max(pc.Cycles, 1)
+	pc.WorkMinutes = max(pc.WorkMinutes, 1) // a hand-edited 0 would end at once
+	name := "Work"
+	if label != "" {
+		name = label
+	}
+
+	for i := 1; i <= cycles; i++ {
+		if done, _ := runCountdown(fmt.Sprintf("Pomodoro %d/%d: %s", i, cycles, name), pc.WorkMinutes*60); !done {
+			printInfo("Pomodoro stopped")
+			return
+		}
+		if err := logPomodoro(pomodoroSession{Time: time.Now(), Label: label, Minutes: pc.WorkMinutes}); err != nil {
+			printError(fmt.Sprintf("Could not save session: %v", err))
+		}
+		count, _ := pomodoroToday()
+
+		brk, minutes := "Short break", pc.ShortBreakMinutes
+		if i == cycles {
+			brk, minutes = "Long break", pc.LongBreakMinutes
+		}
+		go notifyAlarm(notification{Tool: "pomodoro", Title: "Pomodoro", Body: fmt.Sprintf("%s done (%d today). %s: %d min", name, count, brk, minutes)})
+		printSuccess(fmt.Sprintf("Session %d/%d done, %d today", i, cycles, count))
+
+		if !yesNo(fmt.Sprintf("Start %s (%d min)?", strings.ToLower(brk), minutes)) {
+			return
+		}
+		if done, _ := runCountdown(fmt.Sprintf("Pomodoro: %s", brk), minutes*60); !done {
+			printInfo("Pomodoro stopped")
+			return
+		}
+		go notifyAlarm(notification{Tool: "pomodoro", Title: "Pomodoro", Body: brk + " over, back to work"})
+
+		if i < cycles && !yesNo(fmt.Sprintf("Start session %d/%d?", i+1, cycles)) {
+			return
+		}
+	}
+	printSuccess("All Pomodoro cycles done")
+}
+
+// pomodoroSummary prints sessions per day and label for the last 7 days
+func pomodoroSummary() {
+	today := startOfDay(time.Now())
+	first := today.AddDate(0, 0, -6)
+	list, err := loadPomodoros(first)
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+
+	perDay := map[string]int{}
+	perLabel := map[string]int{}
+	total := 0
+	for _, s := range list {
+		perDay[s.Time.Format("2006-01-02")]++
+		label := s.Label
+		if label == "" {
+			label = "(no label)"
+		}
+		perLabel[label] += s.Minutes
+		total += s.Minutes
+	}
+
+	printCommandTitle("Pomodoro - Last 7 days")
+	most := 1
+	for _, n := range perDay {
+		most = max(most, n)
+	}
+	for d := first; !d.After(today); d = d.AddDate(0, 0, 1) {
+		n := perDay[d.Format("2006-01-02")]
+		bar := strings.Repeat("█", n*20/most)
+		fmt.Printf(" %s %s%-20s%s %d\n", d.Format("Mon 02 Jan"), GREEN, bar, RC, n)
+	}
+	line()
+
+	labels := make([]string, 0, len(perLabel))
+	for l := range perLabel {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool { return perLabel[labels[i]] > perLabel[labels[j]] })
+	for _, l := range labels {
+		fmt.Printf(" %s%-30s%s %4d min\n", YELLOW, l, RC, perLabel[l])
+	}
+	fmt.Printf(" %d sessions, %d min (%.1f h) in total\n", len(list), total, float64(total)/60)
+}
+
+// pomodoroSettings edits the lengths and the cycle count.
+// Empty input keeps the current value.
+func pomodoroSettings() {
+	pc := &config.Pomodoro
+	ask := func(what string, v *int) {
+		fmt.Printf("%s [%d]%s", what, *v, PROMPT)
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		if input == "" {
+			return
+		}
+		if n, err := strconv.Atoi(input); err == nil && n > 0 && n <= 24*60 {
+			*v = n
+		} else {
+			printError("Invalid number, keeping " + strconv.Itoa(*v))
+		}
+	}
+	ask("Work minutes", &pc.WorkMinutes)
+	ask("Short break minutes", &pc.ShortBreakMinutes)
+	ask("Long break minutes", &pc.LongBreakMinutes)
+	ask("Sessions before long break", &pc.Cycles)
+
+	if err := saveConfig(); err != nil {
+		printError(fmt.Sprintf("Could not save config: %v", err))
+		return
+	}
+	printSuccess("Pomodoro settings saved")
+}
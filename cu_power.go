@@ -0,0 +1,227 @@
+// #############################################
+// CrunchyUtils - Power Actions
+//
+// This file contains:
+// - Suspend, hibernate, hybrid sleep, lock screen
+//   and log out, now or after a countdown
+// - Support detection through logind (CanSuspend,
+//   CanHibernate, CanHybridSleep)
+//
+// Unsupported actions are greyed out in the Power
+// menu. The shutdown and reboot commands are here as
+// well, their timers are in cu_tools.go, scheduling
+// by the OS in cu_powersched.go, the conditional
+// triggers in cu_powertrigger.go and the warnings
+// before a shutdown in cu_powerwarn.go.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// powerConfig is the "power" section of the config file
+type powerConfig struct {
+	FinalWarningSeconds int    `json:"final_warning_seconds"` // countdown after a trigger fired
+	WarningMinutes      []int  `json:"warning_minutes"`       // before a shutdown/reboot timer ends
+	Wall                bool   `json:"wall"`                  // send warnings to all logged-in users
+	GraceSeconds        int    `json:"grace_seconds"`         // last chance to abort, 0 = none
+	PreShutdownHook     string `json:"pre_shutdown_hook"`     // script run right before, "" = none
+}
+
+func defaultPowerConfig() powerConfig {
+	return powerConfig{
+		FinalWarningSeconds: 60,
+		WarningMinutes:      []int{30, 10, 1},
+		Wall:                true,
+		GraceSeconds:        15,
+	}
+}
+
+// powerAction is something the power menu can do to the machine
+type powerAction struct {
+	Key    rune
+	Name   string // menu label
+	Verb   string // used in messages, "suspend"
+	Logind string // logind method telling if it is supported, "" = none
+}
+
+// Shutdown and reboot have their own menu entries (timer, scheduled)
+var (
+	shutdownAction = powerAction{'1', "Shutdown", "shutdown", ""}
+	rebootAction   = powerAction{'2', "Reboot", "reboot", ""}
+)
+
+// powerActions are the entries with a now-or-timer choice
+var powerActions = []powerAction{
+	{'5', "Suspend", "suspend", "CanSuspend"},
+	{'6', "Hibernate", "hibernate", "CanHibernate"},
+	{'7', "Hybrid sleep", "hybrid-sleep", "CanHybridSleep"},
+	{'8', "Lock screen", "lock", ""},
+	{'9', "Log out", "logout", ""},
+}
+
+// parseLogindAnswer reads a busctl reply like `s "challenge"`
+func parseLogindAnswer(out string) string {
+	_, value, ok := strings.Cut(strings.TrimSpace(out), " ")
+	if !ok {
+		return ""
+	}
+	return strings.Trim(value, `"`)
+}
+
+// logindCan asks logind whether method (CanSuspend, ...) is possible.
+// "challenge" means allowed after authentication, that counts as yes.
+func logindCan(method string) bool {
+	out, err := runCommand([]string{"busctl", "call", "--system",
+		"org.freedesktop.login1", "/org/freedesktop/login1",
+		"org.freedesktop.login1.Manager", method})
+	if err != nil {
+		return false
+	}
+	answer := parseLogindAnswer(out)
+	return answer == "yes" || answer == "challenge"
+}
+
+// Supported reports whether the action works on this system
+func (a powerAction) Supported() bool {
+	if a.Verb == "shutdown" || a.Verb == "reboot" {
+		return true
+	}
+	if goos == "windows" {
+		return a.Verb != "hybrid-sleep" // no command for it
+	}
+	if !systemdRunning() {
+		return false
+	}
+	if a.Logind != "" {
+		return logindCan(a.Logind)
+	}
+	_, err := exec.LookPath("loginctl")
+	return err == nil
+}
+
+// Command returns the command that runs the action now
+func (a powerAction) Command() []string {
+	if goos == "windows" {
+		switch a.Verb {
+		case "shutdown":
+			return []string{"shutdown", "/s", "/f", "/t", "0"}
+		case "reboot":
+			return []string{"shutdown", "/r", "/f", "/t", "0"}
+		case "suspend":
+			// rundll32 powrprof.dll,SetSuspendState cannot pass its
+			// arguments and hibernates when hibernation is on
+			return []string{"powershell", "-NoProfile", "-Command",
+				"Add-Type -AssemblyName System.Windows.Forms; [System.Windows.Forms.Application]::SetSuspendState('Suspend', $false, $false)"}
+		case "hibernate":
+			return []string{"shutdown", "/h"}
+		case "lock":
+			return []string{"rundll32.exe", "user32.dll,LockWorkStation"}
+		case "logout":
+			return []string{"shutdown", "/l"}
+		}
+		return nil
+	}
+
+	switch a.Verb {
+	case "shutdown":
+		return []string{"shutdown", "-h", "now"}
+	case "reboot":
+		return []string{"shutdown", "-r", "now"}
+	case "lock", "logout":
+		// Our own session if we know it, else all sessions of the user
+		verb := map[string]string{"lock": "lock-session", "logout": "terminate-session"}[a.Verb]
+		if id := os.Getenv("XDG_SESSION_ID"); id != "" {
+			return []string{"loginctl", verb, id}
+		}
+		if a.Verb == "lock" {
+			return []string{"loginctl", "lock-sessions"}
+		}
+		u, err := user.Current()
+		if err != nil {
+			return nil
+		}
+		return []string{"loginctl", "terminate-user", u.Username}
+	}
+	return []string{"systemctl", a.Verb}
+}
+
+// powerSupport checks all actions once per menu draw.
+// busctl is fast, but not fast enough to run per key press.
+func powerSupport() map[rune]bool {
+	supported := make(map[rune]bool, len(powerActions))
+	for _, a := range powerActions {
+		supported[a.Key] = a.Supported()
+	}
+	return supported
+}
+
+// printPowerActions writes the menu lines, unsupported ones greyed out
+func printPowerActions(supported map[rune]bool) {
+	for _, a := range powerActions {
+		if supported[a.Key] {
+			fmt.Printf(" [%c] - %s%s%s\n", a.Key, YELLOW, a.Name, RC)
+		} else {
+			fmt.Printf(" %s[%c] - %s (not supported)%s\n", GREY, a.Key, a.Name, RC)
+		}
+	}
+}
+
+// findPowerAction returns the action for a menu key
+func findPowerAction(key rune) (powerAction, bool) {
+	for _, a := range powerActions {
+		if a.Key == key {
+			return a, true
+		}
+	}
+	return powerAction{}, false
+}
+
+// powerActionMenu runs a, right away or after a countdown
+func powerActionMenu(a powerAction, supported bool) {
+	if !supported {
+		printError(a.Name + " is not supported on this system")
+		return
+	}
+	if !yesNo(a.Name + " now? (no = set a timer)") {
+		secs, _, ok := askTimerTime("Enter time for " + a.Verb + " timer")
+		if !ok {
+			return
+		}
+		done, late := runCountdown(a.Name+" Timer", secs)
+		if !done {
+			printInfo("Cancelled " + a.Verb + " timer")
+			return
+		}
+		printLate(late)
+		notifyAlarm(notification{Tool: "power", Title: "Power timer", Body: "Timer finished, executing " + a.Verb})
+		time.Sleep(2 * time.Second)
+	}
+	runPowerAction(a)
+}
+
+// runPowerAction executes a and reports failures.
+// Shutdown and reboot run the pre-shutdown hook first.
+func runPowerAction(a powerAction) {
+	cmd := a.Command()
+	if cmd == nil {
+		printError(a.Name + " is not supported on this system")
+		return
+	}
+	if a.Verb == "shutdown" || a.Verb == "reboot" {
+		runPreShutdownHook(a)
+	}
+	printInfo("Executing " + a.Verb + "...")
+	if _, err := runCommand(cmd); err != nil {
+		printError(err.Error())
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLogindAnswer(t *testing.T) {
+	for out, want := range map[string]string{
+		"s \"yes\"\n":     "yes",
+		"s \"challenge\"": "challenge",
+		"s \"na\"":        "na",
+		"":                "",
+	} {
+		if got := parseLogindAnswer(out); got != want {
+			t.Errorf("%q: got %q, want %q", out, got, want)
+		}
+	}
+}
+
+func TestWindowsSuspendCommand(t *testing.T) {
+	saved := goos
+	defer func() { goos = saved }()
+	goos = "windows"
+
+	a, _ := findPowerAction('5')
+	cmd := strings.Join(a.Command(), " ")
+	// rundll32 hibernates instead when hibernation is on
+	if strings.Contains(cmd, "rundll32") || !strings.Contains(cmd, "SetSuspendState('Suspend'") {
+		t.Errorf("got %q, want a real suspend", cmd)
+	}
+}
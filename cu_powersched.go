@@ -0,0 +1,362 @@
+// #############################################
+// CrunchyUtils - Scheduled Power Actions
+//
+// This file contains:
+// - Shutdown/reboot scheduled by the OS, so it
+//   survives closing CrunchyUtils
+// - Listing and cancelling pending actions
+// - The power line in the banner
+//
+// Linux with systemd gets a transient timer unit
+// (systemd-run), the target time is part of the unit
+// name so it can be listed again later. Without
+// systemd `shutdown -h +N` is used, Windows gets
+// `shutdown /s /t N`. Pending `shutdown` schedules of
+// other users show up too (/run/systemd/shutdown).
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ways a power action can be scheduled
+const (
+	viaSystemd  = "systemd"
+	viaShutdown = "shutdown"
+	viaWindows  = "windows"
+)
+
+// powerUnitPrefix starts the names of our transient units
+const powerUnitPrefix = "crunchyutils-"
+
+// shutdownScheduledFile is where systemd keeps a pending `shutdown +N`
+const shutdownScheduledFile = "/run/systemd/shutdown/scheduled"
+
+// scheduledPower is a power action the OS will run
+type scheduledPower struct {
+	Action string    `json:"action"` // poweroff or reboot
+	When   time.Time `json:"when"`
+	Via    string    `json:"via"`
+	Unit   string    `json:"unit,omitempty"` // systemd timer, "" otherwise
+}
+
+// Label is the action as shown to the user
+func (s scheduledPower) Label() string {
+	if s.Action == "poweroff" {
+		return "shutdown"
+	}
+	return s.Action
+}
+
+// powerUnitName returns the transient unit name for action at when
+func powerUnitName(action string, when time.Time) string {
+	return fmt.Sprintf("%s%s-%d", powerUnitPrefix, action, when.Unix())
+}
+
+// parsePowerUnit reads action and time back from a unit name
+// like crunchyutils-poweroff-1760853600.timer
+func parsePowerUnit(name string) (scheduledPower, bool) {
+	base, ok := strings.CutSuffix(name, ".timer")
+	if !ok {
+		return scheduledPower{}, false
+	}
+	base, ok = strings.CutPrefix(base, powerUnitPrefix)
+	if !ok {
+		return scheduledPower{}, false
+	}
+	action, stamp, ok := strings.Cut(base, "-")
+	if !ok || (action != "poweroff" && action != "reboot") {
+		return scheduledPower{}, false
+	}
+	sec, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil {
+		return scheduledPower{}, false
+	}
+	return scheduledPower{Action: action, When: time.Unix(sec, 0), Via: viaSystemd, Unit: name}, true
+}
+
+// parsePowerUnits picks our timers from `systemctl list-units --plain --no-legend`
+func parsePowerUnits(out string) []scheduledPower {
+	var list []scheduledPower
+	for _, l := range strings.Split(out, "\n") {
+		fields := strings.Fields(l)
+		if len(fields) == 0 {
+			continue
+		}
+		if s, ok := parsePowerUnit(fields[0]); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// parseShutdownScheduled reads the key=value file systemd writes
+// for a pending `shutdown`, MODE is poweroff, reboot, halt, ...
+func parseShutdownScheduled(data string) (scheduledPower, bool) {
+	s := scheduledPower{Via: viaShutdown}
+	for _, l := range strings.Split(data, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(l), "=")
+		switch key {
+		case "USEC":
+			usec, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return scheduledPower{}, false
+			}
+			s.When = time.UnixMicro(usec)
+		case "MODE":
+			s.Action = value
+		}
+	}
+	return s, s.Action != "" && !s.When.IsZero()
+}
+
+// systemdRunning reports whether systemd is the init system
+func systemdRunning() bool {
+	_, err := os.Stat("/run/systemd/system")
+	return err == nil
+}
+
+// powerRecordPath keeps schedules the OS cannot list (Windows, no systemd)
+func powerRecordPath() string {
+	return dataPath("power-schedule.json")
+}
+
+// loadPowerRecords reads the recorded schedules that are still ahead
+func loadPowerRecords() []scheduledPower {
+	data, err := os.ReadFile(powerRecordPath())
+	if err != nil {
+		return nil
+	}
+	var all, list []scheduledPower
+	json.Unmarshal(data, &all)
+	for _, s := range all {
+		if s.When.After(time.Now()) {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// savePowerRecords writes the recorded schedules, an empty list removes the file
+func savePowerRecords(list []scheduledPower) error {
+	if len(list) == 0 {
+		err := os.Remove(powerRecordPath())
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	path := powerRecordPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// schedulePower hands action (poweroff or reboot) at when to the OS
+func schedulePower(action string, when time.Time) (scheduledPower, error) {
+	defer invalidatePowerStatus()
+
+	_, errRun := exec.LookPath("systemd-run")
+	switch {
+	case goos == "windows":
+		flag := "/s"
+		if action == "reboot" {
+			flag = "/r"
+		}
+		secs := int(math.Ceil(time.Until(when).Seconds()))
+		if _, err := runCommand([]string{"shutdown", flag, "/f", "/t", strconv.Itoa(secs)}); err != nil {
+			return scheduledPower{}, err
+		}
+		s := scheduledPower{Action: action, When: time.Now().Add(time.Duration(secs) * time.Second), Via: viaWindows}
+		return s, savePowerRecords([]scheduledPower{s}) // Windows has one pending shutdown at most
+
+	case systemdRunning() && errRun == nil:
+		when = when.Truncate(time.Second)
+		unit := powerUnitName(action, when)
+		_, err := runCommand([]string{"systemd-run",
+			"--unit=" + unit,
+			"--description=CrunchyUtils scheduled " + action,
+			"--on-calendar=" + when.Format("2006-01-02 15:04:05"),
+			"--timer-property=AccuracySec=1s",
+			"systemctl", action})
+		return scheduledPower{Action: action, When: when, Via: viaSystemd, Unit: unit + ".timer"}, err
+	}
+
+	// shutdown(8) counts in whole minutes
+	flag := "-h"
+	if action == "reboot" {
+		flag = "-r"
+	}
+	mins := max(int(math.Ceil(time.Until(when).Minutes())), 1)
+	if _, err := runCommand([]string{"shutdown", flag, "+" + strconv.Itoa(mins)}); err != nil {
+		return scheduledPower{}, err
+	}
+	s := scheduledPower{Action: action, When: time.Now().Add(time.Duration(mins) * time.Minute), Via: viaShutdown}
+	if !systemdRunning() {
+		return s, savePowerRecords([]scheduledPower{s})
+	}
+	return s, nil
+}
+
+// listScheduledPower returns the pending power actions, soonest first
+func listScheduledPower() []scheduledPower {
+	var list []scheduledPower
+	if goos == "windows" || !systemdRunning() {
+		list = loadPowerRecords()
+	} else {
+		if data, err := os.ReadFile(shutdownScheduledFile); err == nil {
+			if s, ok := parseShutdownScheduled(string(data)); ok {
+				list = append(list, s)
+			}
+		}
+		out, err := runCommand([]string{"systemctl", "list-units", "--all", "--plain", "--no-legend", "--no-pager", "--type=timer", powerUnitPrefix + "*"})
+		if err == nil {
+			list = append(list, parsePowerUnits(out)...)
+		}
+	}
+	// Elapsed systemd timers stay listed until they are collected
+	now := time.Now()
+	pending := list[:0]
+	for _, s := range list {
+		if s.When.After(now) {
+			pending = append(pending, s)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].When.Before(pending[j].When) })
+	return pending
+}
+
+// cancelScheduledPower stops a pending power action
+func cancelScheduledPower(s scheduledPower) error {
+	defer invalidatePowerStatus()
+
+	var cmd []string
+	switch s.Via {
+	case viaSystemd:
+		cmd = []string{"systemctl", "stop", s.Unit}
+	case viaWindows:
+		cmd = []string{"shutdown", "/a"}
+	default:
+		cmd = []string{"shutdown", "-c"}
+	}
+	if _, err := runCommand(cmd); err != nil {
+		return err
+	}
+	if s.Via != viaSystemd {
+		return savePowerRecords(nil)
+	}
+	return nil
+}
+
+// The banner is drawn often, the pending list is read at most every few seconds
+var powerStatus struct {
+	sync.Mutex
+	list []scheduledPower
+	read time.Time
+}
+
+// invalidatePowerStatus makes the next banner read the list again
+func invalidatePowerStatus() {
+	powerStatus.Lock()
+	powerStatus.read = time.Time{}
+	powerStatus.Unlock()
+}
+
+// untilText is a short "in ..." for the banner: "<1m", "5m", "2h0m"
+func untilText(d time.Duration) string {
+	if d < time.Minute {
+		return "<1m"
+	}
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
+
+// powerStatusLine is the banner line for pending power actions, "" if none
+func powerStatusLine() string {
+	powerStatus.Lock()
+	defer powerStatus.Unlock()
+	if time.Since(powerStatus.read) > 5*time.Second {
+		powerStatus.list = listScheduledPower()
+		powerStatus.read = time.Now()
+	}
+
+	var next *scheduledPower
+	for i, s := range powerStatus.list {
+		if s.When.After(time.Now()) {
+			next = &powerStatus.list[i]
+			break
+		}
+	}
+	if next == nil {
+		return ""
+	}
+	line := fmt.Sprintf("%s%s%s at %s (in %s)", RED, next.Label(), RC, next.When.Format("Mon 15:04"), untilText(time.Until(next.When)))
+	if n := len(powerStatus.list); n > 1 {
+		line += fmt.Sprintf(" (+%d more)", n-1)
+	}
+	return line
+}
+
+// printScheduledPower lists the pending power actions with numbers
+func printScheduledPower(list []scheduledPower) {
+	if len(list) == 0 {
+		fmt.Printf(" No scheduled shutdown or reboot\n")
+		return
+	}
+	for i, s := range list {
+		fmt.Printf(" #%-3d %s%-9s%s %s  via %s\n", i+1, RED, s.Label(), RC, s.When.Format("Mon 02 Jan 15:04:05"), s.Via)
+	}
+}
+
+// schedulePowerPrompt asks for the time and schedules action with the OS
+func schedulePowerPrompt(action string) {
+	_, target, ok := askTimerTime("Enter time for the scheduled " + scheduledPower{Action: action}.Label())
+	if !ok {
+		return
+	}
+	s, err := schedulePower(action, target)
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	printSuccess(fmt.Sprintf("%s scheduled for %s via %s, it runs even if CrunchyUtils is closed", s.Label(), s.When.Format("Mon 02 Jan 15:04:05"), s.Via))
+}
+
+// cancelPowerPrompt asks which pending action to cancel
+func cancelPowerPrompt(list []scheduledPower) {
+	if len(list) == 0 {
+		printInfo("Nothing to cancel")
+		return
+	}
+	fmt.Printf("Number to cancel%s", PROMPT)
+	input, _ := reader.ReadString('\n')
+	n, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || n < 1 || n > len(list) {
+		printError("Invalid number")
+		return
+	}
+	if err := cancelScheduledPower(list[n-1]); err != nil {
+		printError(err.Error())
+		return
+	}
+	printSuccess("Cancelled scheduled " + list[n-1].Label())
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPowerUnitName(t *testing.T) {
+	when := time.Date(2025, 3, 10, 22, 30, 0, 0, time.Local)
+	name := powerUnitName("poweroff", when) + ".timer"
+
+	s, ok := parsePowerUnit(name)
+	if !ok {
+		t.Fatalf("%s not recognized", name)
+	}
+	if s.Action != "poweroff" || !s.When.Equal(when) || s.Via != viaSystemd || s.Unit != name {
+		t.Errorf("got %+v", s)
+	}
+
+	for _, bad := range []string{
+		"crunchyutils-poweroff-1741645800.service",
+		"crunchyutils-suspend-1741645800.timer",
+		"crunchyutils-reboot-soon.timer",
+		"logrotate.timer",
+	} {
+		if _, ok := parsePowerUnit(bad); ok {
+			t.Errorf("%s: want no match", bad)
+		}
+	}
+}
+
+func TestParsePowerUnits(t *testing.T) {
+	out := `crunchyutils-reboot-1741645800.timer   loaded active waiting CrunchyUtils scheduled reboot
+crunchyutils-poweroff-1741600000.timer loaded active waiting CrunchyUtils scheduled poweroff
+`
+	list := parsePowerUnits(out)
+	if len(list) != 2 {
+		t.Fatalf("got %d units, want 2", len(list))
+	}
+	if list[0].Action != "reboot" || list[0].When.Unix() != 1741645800 || list[1].Action != "poweroff" {
+		t.Errorf("got %+v", list)
+	}
+}
+
+func TestParseShutdownScheduled(t *testing.T) {
+	s, ok := parseShutdownScheduled("USEC=1741645800000000\nWARN_WALL=1\nMODE=reboot\n")
+	if !ok {
+		t.Fatal("not parsed")
+	}
+	if s.Action != "reboot" || s.When.Unix() != 1741645800 || s.Via != viaShutdown {
+		t.Errorf("got %+v", s)
+	}
+
+	for _, bad := range []string{"", "MODE=poweroff\n", "USEC=abc\nMODE=poweroff\n"} {
+		if _, ok := parseShutdownScheduled(bad); ok {
+			t.Errorf("%q: want no schedule", bad)
+		}
+	}
+}
+
+func TestUntilText(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                             "<1m",
+		29 * time.Second:              "<1m",
+		59 * time.Second:              "<1m",
+		90 * time.Second:              "2m",
+		2*time.Hour + 10*time.Second:  "2h0m",
+		26*time.Hour + 31*time.Minute: "26h31m",
+	} {
+		if got := untilText(d); got != want {
+			t.Errorf("%s: got %q, want %q", d, got, want)
+		}
+	}
+}
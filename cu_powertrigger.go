@@ -0,0 +1,368 @@
+// #############################################
+// CrunchyUtils - Power Triggers
+//
+// This file contains:
+// - Conditional power actions: run shutdown, reboot,
+//   suspend, ... once
+//   - a process (PID or name) exits
+//   - CPU stays below X% for N minutes
+//   - network stays below X KB/s for N minutes
+//   - a file stops growing for N minutes
+// - The watch screen and the final warning countdown
+//
+// "power.final_warning_seconds" in the config sets the
+// last countdown, it can be cancelled like any timer.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eiannone/keyboard"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// triggerInterval is how often a trigger checks its condition
+const triggerInterval = 5 * time.Second
+
+// powerTrigger is a condition the watch screen waits for
+type powerTrigger struct {
+	Desc  string                             // "ffmpeg exits"
+	Check func(now time.Time) (bool, string) // met, current status
+}
+
+// quietWindow tracks how long a value stayed below its threshold
+type quietWindow struct {
+	need  time.Duration
+	since time.Time // zero while not quiet
+}
+
+// Update records one sample and reports whether it was quiet long enough
+func (q *quietWindow) Update(now time.Time, quiet bool) bool {
+	if !quiet {
+		q.since = time.Time{}
+		return false
+	}
+	if q.since.IsZero() {
+		q.since = now
+	}
+	return now.Sub(q.since) >= q.need
+}
+
+// Status describes the progress, e.g. "quiet for 00:01:20 of 00:05:00"
+func (q *quietWindow) Status(now time.Time) string {
+	if q.since.IsZero() {
+		return "not quiet"
+	}
+	return fmt.Sprintf("quiet for %s of %s", formatTime(int(now.Sub(q.since).Seconds())), formatTime(int(q.need.Seconds())))
+}
+
+// processTrigger fires when the process with this PID, or all
+// processes with this name, are gone
+func processTrigger(target string) (*powerTrigger, error) {
+	if pid, err := strconv.Atoi(target); err == nil {
+		p, err := process.NewProcess(int32(pid))
+		if err != nil {
+			return nil, fmt.Errorf("no process with PID %d", pid)
+		}
+		name, _ := p.Name()
+		created, _ := p.CreateTime()
+		return &powerTrigger{
+			Desc: fmt.Sprintf("PID %d (%s) exits", pid, name),
+			Check: func(time.Time) (bool, string) {
+				if pidGone(int32(pid), created) {
+					return true, "exited"
+				}
+				return false, "running"
+			},
+		}, nil
+	}
+
+	count := func() int {
+		procs, _ := process.Processes()
+		n := 0
+		for _, p := range procs {
+			name, err := p.Name()
+			if err == nil && (strings.EqualFold(name, target) || strings.EqualFold(name, target+".exe")) {
+				n++
+			}
+		}
+		return n
+	}
+	if count() == 0 {
+		return nil, fmt.Errorf("no process named %s", target)
+	}
+	return &powerTrigger{
+		Desc: "all " + target + " processes exit",
+		Check: func(time.Time) (bool, string) {
+			if n := count(); n > 0 {
+				return false, fmt.Sprintf("%d running", n)
+			}
+			return true, "all exited"
+		},
+	}, nil
+}
+
+// pidGone reports whether the process that had pid when it was
+// created at created (ms) has exited. The checks are seconds apart,
+// a new process may have taken the PID by then. Zombies count as
+// exited, their parent just did not collect them yet.
+func pidGone(pid int32, created int64) bool {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return true
+	}
+	if ct, err := p.CreateTime(); err == nil && created != 0 && ct != created {
+		return true
+	}
+	status, err := p.Status()
+	return err == nil && slices.Contains(status, process.Zombie)
+}
+
+// cpuTrigger fires when CPU usage stays below percent for d
+func cpuTrigger(percent float64, d time.Duration) *powerTrigger {
+	cpu.Percent(0, false) // the next call measures from here
+	q := &quietWindow{need: d}
+	return &powerTrigger{
+		Desc: fmt.Sprintf("CPU stays below %.0f%% for %s", percent, formatTime(int(d.Seconds()))),
+		Check: func(now time.Time) (bool, string) {
+			p, err := cpu.Percent(0, false)
+			if err != nil || len(p) == 0 {
+				return false, "CPU usage unavailable"
+			}
+			met := q.Update(now, p[0] < percent)
+			return met, fmt.Sprintf("CPU %.0f%%, %s", p[0], q.Status(now))
+		},
+	}
+}
+
+// netTrigger fires when receive plus send stays below kbs KB/s for d
+func netTrigger(kbs float64, d time.Duration) *powerTrigger {
+	q := &quietWindow{need: d}
+	prev := sysSnapshot{Time: time.Now(), Net: getNetCounters()}
+	return &powerTrigger{
+		Desc: fmt.Sprintf("network stays below %.0f KB/s for %s", kbs, formatTime(int(d.Seconds()))),
+		Check: func(now time.Time) (bool, string) {
+			cur := sysSnapshot{Time: now, Net: getNetCounters()}
+			recv, sent := cur.NetRates(prev)
+			prev = cur
+			rate := (recv + sent) / 1024
+			met := q.Update(now, rate < kbs)
+			return met, fmt.Sprintf("%.1f KB/s, %s", rate, q.Status(now))
+		},
+	}
+}
+
+// fileTrigger fires when the file did not grow for d.
+// A file that disappears counts as done, downloads are often
+// renamed when they are complete.
+func fileTrigger(path string, d time.Duration) (*powerTrigger, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	q := &quietWindow{need: d}
+	return &powerTrigger{
+		Desc: fmt.Sprintf("%s stops growing for %s", path, formatTime(int(d.Seconds()))),
+		Check: func(now time.Time) (bool, string) {
+			fi, err := os.Stat(path)
+			if errors.Is(err, os.ErrNotExist) {
+				return true, "file is gone"
+			}
+			if err != nil {
+				return false, err.Error()
+			}
+			grown := fi.Size() != size
+			size = fi.Size()
+			met := q.Update(now, !grown)
+			return met, fmt.Sprintf("%s, %s", formatBytes(float64(size)), q.Status(now))
+		},
+	}, nil
+}
+
+// askFloat asks for a positive number, def on empty input
+func askFloat(prompt string, def float64) (float64, bool) {
+	fmt.Printf("%s [%g]%s", prompt, def, PROMPT)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return def, true
+	}
+	v, err := strconv.ParseFloat(input, 64)
+	if err != nil || v <= 0 {
+		printError("Invalid number")
+		return 0, false
+	}
+	return v, true
+}
+
+// askTriggerMinutes asks how long a condition must last
+func askTriggerMinutes(def float64) (time.Duration, bool) {
+	m, ok := askFloat("For how many minutes", def)
+	return time.Duration(m * float64(time.Minute)), ok
+}
+
+// askTriggerAction lets the user pick what happens when a trigger fires
+func askTriggerAction() (powerAction, bool) {
+	actions := append([]powerAction{shutdownAction, rebootAction}, powerActions...)
+	supported := powerSupport()
+	fmt.Printf("Then:\n")
+	for _, a := range actions {
+		if a.Key == '1' || a.Key == '2' || supported[a.Key] {
+			fmt.Printf(" [%c] - %s%s%s\n", a.Key, YELLOW, a.Name, RC)
+		}
+	}
+	fmt.Printf(" [0] - %sCancel%s\n", RED, RC)
+
+	c, _, err := keyboard.GetSingleKey()
+	if err != nil {
+		return powerAction{}, false
+	}
+	for _, a := range actions {
+		if a.Key == c && (c == '1' || c == '2' || supported[c]) {
+			return a, true
+		}
+	}
+	return powerAction{}, false
+}
+
+// powerTriggerMenu sets up a trigger, waits for it and runs the action
+func powerTriggerMenu() {
+	printCommandTitle("Power Trigger")
+	fmt.Printf(" [0] - %sReturn%s\n", RED, RC)
+	fmt.Printf(" [1] - %sWhen a process exits%s (PID or name)\n", YELLOW, RC)
+	fmt.Printf(" [2] - %sWhen CPU stays low%s\n", YELLOW, RC)
+	fmt.Printf(" [3] - %sWhen the network stays quiet%s\n", YELLOW, RC)
+	fmt.Printf(" [4] - %sWhen a file stops growing%s\n", YELLOW, RC)
+	line()
+	fmt.Printf("Press key (0-4)\n")
+
+	c, _, err := keyboard.GetSingleKey()
+	if err != nil {
+		return
+	}
+
+	var trigger *powerTrigger
+	switch c {
+	case '1':
+		fmt.Printf("PID or process name%s", PROMPT)
+		input, _ := reader.ReadString('\n')
+		trigger, err = processTrigger(strings.TrimSpace(input))
+	case '2':
+		percent, ok := askFloat("CPU below percent", 10)
+		if !ok {
+			return
+		}
+		d, ok := askTriggerMinutes(5)
+		if !ok {
+			return
+		}
+		trigger = cpuTrigger(percent, d)
+	case '3':
+		kbs, ok := askFloat("Network below KB/s", 50)
+		if !ok {
+			return
+		}
+		d, ok := askTriggerMinutes(5)
+		if !ok {
+			return
+		}
+		trigger = netTrigger(kbs, d)
+	case '4':
+		fmt.Printf("File path%s", PROMPT)
+		path, _ := reader.ReadString('\n')
+		d, ok := askTriggerMinutes(2)
+		if !ok {
+			return
+		}
+		trigger, err = fileTrigger(strings.TrimSpace(path), d)
+	default:
+		return
+	}
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+
+	a, ok := askTriggerAction()
+	if !ok {
+		printInfo("Cancelled power trigger")
+		return
+	}
+	if !watchTrigger(trigger, a) {
+		printInfo("Cancelled power trigger")
+		return
+	}
+
+	// Last chance to stop it
+	warn := max(config.Power.FinalWarningSeconds, 5)
+	go notifyAlarm(notification{Tool: "power", Title: "Power trigger", Body: fmt.Sprintf("%s: %s in %d seconds", trigger.Desc, a.Verb, warn), Urgency: urgencyCritical})
+	done, late := runCountdown("Final warning: "+a.Name, warn)
+	if !done {
+		printInfo("Cancelled " + a.Verb)
+		return
+	}
+	printLate(late)
+	runPowerAction(a)
+}
+
+// watchTrigger shows the trigger status until it fires (true)
+// or the user cancels (false)
+func watchTrigger(t *powerTrigger, a powerAction) bool {
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		printError(fmt.Sprintf("Keyboard unavailable: %v", err))
+		return false
+	}
+	defer keyboard.Close()
+
+	scr := newScreen()
+	defer scr.Close()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	started := time.Now()
+	var checked time.Time
+	status := "checking..."
+	for {
+		if now := time.Now(); now.Sub(checked) >= triggerInterval {
+			checked = now
+			met, s := t.Check(now)
+			if met {
+				return true
+			}
+			status = s
+		}
+
+		fprintCommandTitle(scr, "Power Trigger")
+		fline(scr)
+		fmt.Fprintf(scr, "\n Waiting until %s%s%s\n", YELLOW, t.Desc, RC)
+		fmt.Fprintf(scr, " Then         %s%s%s\n", RED, a.Name, RC)
+		fmt.Fprintf(scr, " Status       %s\n", status)
+		fmt.Fprintf(scr, " Watching for %s\n\n", formatTime(int(time.Since(started).Seconds())))
+		fline(scr)
+		fmt.Fprintf(scr, "[Enter] cancel\n")
+		scr.Flush()
+
+		select {
+		case <-ticker.C:
+		case <-termResized:
+		case ev := <-keys:
+			if isStopKey(ev) {
+				return false
+			}
+		}
+	}
+}
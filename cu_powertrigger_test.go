@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+func TestQuietWindow(t *testing.T) {
+	start := time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC)
+	q := &quietWindow{need: time.Minute}
+
+	steps := []struct {
+		after time.Duration
+		quiet bool
+		met   bool
+	}{
+		{0, true, false},
+		{30 * time.Second, true, false},
+		{40 * time.Second, false, false}, // a busy sample starts over
+		{50 * time.Second, true, false},
+		{100 * time.Second, true, false},
+		{110 * time.Second, true, true},
+	}
+	for _, s := range steps {
+		if got := q.Update(start.Add(s.after), s.quiet); got != s.met {
+			t.Errorf("after %v: got %v, want %v", s.after, got, s.met)
+		}
+	}
+}
+
+func TestFileTrigger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "download.part")
+	if err := os.WriteFile(path, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	trig, err := fileTrigger(path, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if met, _ := trig.Check(start); met {
+		t.Fatal("met right away")
+	}
+
+	// Growth resets the window
+	os.WriteFile(path, []byte("abcdef"), 0o644)
+	if met, _ := trig.Check(start.Add(50 * time.Second)); met {
+		t.Fatal("met while growing")
+	}
+	if met, _ := trig.Check(start.Add(60 * time.Second)); met { // quiet from here
+		t.Fatal("met on the first quiet sample")
+	}
+	if met, _ := trig.Check(start.Add(110 * time.Second)); met {
+		t.Fatal("met before a quiet minute")
+	}
+	if met, _ := trig.Check(start.Add(120 * time.Second)); !met {
+		t.Fatal("not met after a quiet minute")
+	}
+
+	// A vanished file counts as done
+	trig, _ = fileTrigger(path, time.Hour)
+	os.Remove(path)
+	if met, status := trig.Check(start); !met {
+		t.Fatalf("not met for a removed file (%s)", status)
+	}
+
+	if _, err := fileTrigger(path, time.Minute); err == nil {
+		t.Error("want an error for a missing file")
+	}
+}
+
+func TestPidGone(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sleep and zombies")
+	}
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	pid := int32(cmd.Process.Pid)
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := p.CreateTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pidGone(pid, created) {
+		t.Error("running process: want not gone")
+	}
+	if !pidGone(pid, created-1000) {
+		t.Error("other create time (PID reused): want gone")
+	}
+
+	// Killed but not collected yet: a zombie
+	cmd.Process.Kill()
+	deadline := time.Now().Add(2 * time.Second)
+	for !pidGone(pid, created) {
+		if time.Now().After(deadline) {
+			t.Fatal("zombie: want gone")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cmd.Wait()
+	if !pidGone(pid, created) {
+		t.Error("collected process: want gone")
+	}
+}
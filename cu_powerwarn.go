@@ -0,0 +1,162 @@
+// #############################################
+// CrunchyUtils - Shutdown Warnings
+//
+// This file contains:
+// - Escalating warnings before a timed shutdown or
+//   reboot (30, 10 and 1 minute by default)
+// - Broadcast to all logged-in users (wall, msg *)
+// - The grace period to abort once the timer ran out
+// - The optional pre-shutdown hook script
+//
+// Set in the "power" section of the config:
+// "warning_minutes", "wall", "grace_seconds" and
+// "pre_shutdown_hook". The hook gets CU_ACTION
+// (shutdown/reboot) in its environment.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hookTimeout is how long the pre-shutdown hook may take
+const hookTimeout = 2 * time.Minute
+
+// noticeShown is how long a sent warning stays on the countdown screen
+const noticeShown = 30 * time.Second
+
+// powerWarnings sends the warnings of one running power timer
+type powerWarnings struct {
+	action powerAction
+	marks  []time.Duration // not yet sent, largest first
+	sent   bool            // at least one warning went out
+	note   string          // shown on the countdown screen
+	until  time.Time       // note is shown until then
+}
+
+// newPowerWarnings takes the configured marks that fall inside total.
+// A 5 minute timer only gets the 1 minute warning.
+func newPowerWarnings(a powerAction, total time.Duration) *powerWarnings {
+	w := &powerWarnings{action: a}
+	for _, m := range config.Power.WarningMinutes {
+		if d := time.Duration(m) * time.Minute; m > 0 && d < total {
+			w.marks = append(w.marks, d)
+		}
+	}
+	sort.Slice(w.marks, func(i, j int) bool { return w.marks[i] > w.marks[j] })
+	return w
+}
+
+// due pops the marks that left has reached. After a jump
+// (minus key, suspend) only one warning is sent for all of them.
+func (w *powerWarnings) due(left time.Duration) bool {
+	hit := false
+	for len(w.marks) > 0 && left <= w.marks[0] {
+		w.marks = w.marks[1:]
+		hit = true
+	}
+	return hit
+}
+
+// Notice is the runCountdownWith callback, it sends due warnings
+// and returns the line for the countdown screen
+func (w *powerWarnings) Notice(left time.Duration) string {
+	if w.due(left) {
+		w.sent = true
+		msg := powerWarningText(w.action, left)
+		go broadcastPower(msg)
+		w.note = "Warning sent: " + msg
+		w.until = time.Now().Add(noticeShown)
+	}
+	if time.Now().Before(w.until) {
+		return w.note
+	}
+	return ""
+}
+
+// Cancelled tells everyone that was warned that nothing happens
+func (w *powerWarnings) Cancelled() {
+	if w.sent {
+		broadcastPower(fmt.Sprintf("The %s was cancelled.", w.action.Verb))
+	}
+}
+
+// powerWarningText is the warning message, e.g.
+// "The system will shut down in 10 minutes (at 22:30)."
+func powerWarningText(a powerAction, left time.Duration) string {
+	what := "shut down"
+	if a.Verb == "reboot" {
+		what = "reboot"
+	}
+	left = left.Round(time.Second) // ticks land just below a mark
+	in := fmt.Sprintf("%d minutes", int(math.Ceil(left.Minutes())))
+	switch {
+	case left < time.Minute:
+		in = fmt.Sprintf("%d seconds", int(math.Ceil(left.Seconds())))
+	case left <= time.Minute+time.Second:
+		in = "1 minute"
+	}
+	return fmt.Sprintf("The system will %s in %s (at %s). Please save your work.", what, in, time.Now().Add(left).Format("15:04"))
+}
+
+// broadcastPower sends msg as a desktop notification (and the other
+// power backends) and, if enabled, to every terminal on the machine
+func broadcastPower(msg string) {
+	n := notification{Tool: "power", Title: "Power warning", Body: msg, Urgency: urgencyCritical}
+	notifyAlarm(n)
+	if !config.Power.Wall {
+		return
+	}
+	cmd := []string{"wall", "CrunchyUtils: " + msg}
+	if goos == "windows" {
+		cmd = []string{"msg", "*", "/time:60", "CrunchyUtils: " + msg}
+	}
+	if _, err := runCommand(cmd); err != nil {
+		logNotifyError(n, fmt.Errorf("broadcast: %w", err))
+	}
+}
+
+// powerGrace is the last chance to abort once the timer ran out.
+// It returns false if the user aborted.
+func powerGrace(a powerAction, w *powerWarnings) bool {
+	grace := config.Power.GraceSeconds
+	if grace <= 0 {
+		return true
+	}
+	w.sent = true
+	go broadcastPower(powerWarningText(a, time.Duration(grace)*time.Second))
+	done, _ := runCountdownWith("Last chance: "+a.Name, grace, func(time.Duration) string {
+		return "Press [Enter] to abort the " + a.Verb
+	})
+	return done
+}
+
+// runPreShutdownHook runs the configured hook before a shutdown or
+// reboot. A failing hook is reported but does not stop the action,
+// an unattended shutdown should not hang on a broken script.
+func runPreShutdownHook(a powerAction) {
+	hook := strings.TrimSpace(config.Power.PreShutdownHook)
+	if hook == "" {
+		return
+	}
+	printInfo("Running pre-shutdown hook " + hook)
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, hook)
+	cmd.Env = append(os.Environ(), "CU_ACTION="+a.Verb)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		printError(fmt.Sprintf("Pre-shutdown hook failed: %v", err))
+	}
+}
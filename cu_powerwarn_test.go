@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPowerWarningMarks(t *testing.T) {
+	saved := config
+	defer func() { config = saved }()
+	config.Power.WarningMinutes = []int{1, 30, 10, 0}
+
+	w := newPowerWarnings(shutdownAction, 20*time.Minute)
+	if len(w.marks) != 2 || w.marks[0] != 10*time.Minute || w.marks[1] != time.Minute {
+		t.Fatalf("got marks %v, want [10m 1m]", w.marks)
+	}
+
+	for _, step := range []struct {
+		left time.Duration
+		want bool
+	}{
+		{15 * time.Minute, false},
+		{10 * time.Minute, true},
+		{9 * time.Minute, false},
+		{30 * time.Second, true}, // jumped, no second warning for it
+		{10 * time.Second, false},
+	} {
+		if got := w.due(step.left); got != step.want {
+			t.Errorf("%s left: got %v, want %v", step.left, got, step.want)
+		}
+	}
+}
+
+func TestPowerWarningText(t *testing.T) {
+	for _, c := range []struct {
+		a    powerAction
+		left time.Duration
+		want string
+	}{
+		{shutdownAction, 10 * time.Minute, "shut down in 10 minutes"},
+		{rebootAction, time.Minute - 150*time.Millisecond, "reboot in 1 minute"},
+		{shutdownAction, 15 * time.Second, "shut down in 15 seconds"},
+	} {
+		if got := powerWarningText(c.a, c.left); !strings.Contains(got, c.want) {
+			t.Errorf("got %q, want it to contain %q", got, c.want)
+		}
+	}
+}
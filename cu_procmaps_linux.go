@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// memoryMapsSummary sums up the memory maps of p
+func memoryMapsSummary(p *process.Process) string {
+	maps, err := p.MemoryMaps(false)
+	if err != nil || maps == nil {
+		return "n/a"
+	}
+
+	var rss, size, swap uint64
+	for _, m := range *maps {
+		rss += m.Rss
+		size += m.Size
+		swap += m.Swap
+	}
+	// gopsutil reports these in KB
+	return fmt.Sprintf("%d regions, size %s, rss %s, swap %s", len(*maps),
+		formatBytes(float64(size*1024)), formatBytes(float64(rss*1024)), formatBytes(float64(swap*1024)))
+}
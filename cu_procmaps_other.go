@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "github.com/shirou/gopsutil/v3/process"
+
+// memoryMapsSummary is only available on Linux
+func memoryMapsSummary(p *process.Process) string {
+	return "n/a"
+}
@@ -0,0 +1,364 @@
+// #############################################
+// CrunchyUtils - Process Tree
+//
+// This file contains:
+// - Process tree built from parent PIDs
+// - Tree view with collapse/expand
+// - Per-process detail page (cmdline, cwd, env,
+//   open files, connections, memory maps, cgroup)
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eiannone/keyboard"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// procNode is one process in the tree
+type procNode struct {
+	PID      int32
+	PPID     int32
+	Name     string
+	User     string
+	Memory   uint64
+	Children []*procNode
+}
+
+// procRow is one visible line of the tree view
+type procRow struct {
+	node   *procNode
+	prefix string // tree drawing like "│ ├─"
+}
+
+// buildProcTree reads all processes and links them by parent PID.
+// Processes whose parent is unknown become roots.
+func buildProcTree() ([]*procNode, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[int32]*procNode, len(procs))
+	for _, p := range procs {
+		n := &procNode{PID: p.Pid}
+		n.PPID, _ = p.Ppid()
+		n.Name, _ = p.Name()
+		n.User, _ = p.Username()
+		if mi, err := p.MemoryInfo(); err == nil {
+			n.Memory = mi.RSS
+		}
+		nodes[p.Pid] = n
+	}
+
+	var roots []*procNode
+	for _, n := range nodes {
+		parent, ok := nodes[n.PPID]
+		if !ok || n.PPID == n.PID {
+			roots = append(roots, n)
+			continue
+		}
+		parent.Children = append(parent.Children, n)
+	}
+
+	// Stable order: by PID everywhere
+	var sortNodes func([]*procNode)
+	sortNodes = func(list []*procNode) {
+		sort.Slice(list, func(i, j int) bool { return list[i].PID < list[j].PID })
+		for _, n := range list {
+			sortNodes(n.Children)
+		}
+	}
+	sortNodes(roots)
+
+	return roots, nil
+}
+
+// flattenProcTree returns the visible rows, skipping children of collapsed nodes
+func flattenProcTree(roots []*procNode, collapsed map[int32]bool) []procRow {
+	var rows []procRow
+
+	var walk func(list []*procNode, indent string)
+	walk = func(list []*procNode, indent string) {
+		for i, n := range list {
+			last := i == len(list)-1
+			branch, next := "├─", "│ "
+			if last {
+				branch, next = "└─", "  "
+			}
+			rows = append(rows, procRow{node: n, prefix: indent + branch})
+			if !collapsed[n.PID] {
+				walk(n.Children, indent+next)
+			}
+		}
+	}
+	walk(roots, "")
+
+	return rows
+}
+
+// processTree shows the process hierarchy.
+// Keys: Up/Down/PgUp/PgDn move, Left/Right/Space collapse & expand,
+// Enter opens the detail page, F5/R reloads, Esc/Q leaves.
+func processTree() {
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		printError(fmt.Sprintf("Keyboard unavailable: %v", err))
+		return
+	}
+	defer keyboard.Close()
+
+	scr := newScreen()
+	defer scr.Close()
+
+	var (
+		roots     []*procNode
+		rows      []procRow
+		collapsed = map[int32]bool{}
+		cursor    int
+		offset    int // first row on screen
+	)
+
+	reload := func() {
+		roots, err = buildProcTree()
+		rows = flattenProcTree(roots, collapsed)
+	}
+	reload()
+
+	for {
+		_, height := termSize()
+		view := max(height-5, 1) // title, lines & footer
+
+		cursor = max(0, min(cursor, len(rows)-1))
+		if cursor < offset {
+			offset = cursor
+		}
+		if cursor >= offset+view {
+			offset = cursor - view + 1
+		}
+
+		fprintCommandTitle(scr, "Process Tree")
+		fline(scr)
+		if err != nil {
+			fmt.Fprintf(scr, "%sCould not read processes: %v%s\n", RED, err, RC)
+		}
+		for i := offset; i < min(offset+view, len(rows)); i++ {
+			r := rows[i]
+			mark := " "
+			if len(r.node.Children) > 0 {
+				mark = "-"
+				if collapsed[r.node.PID] {
+					mark = "+"
+				}
+			}
+			text := fmt.Sprintf("%s%s %s%s%s [%d] %s", r.prefix, mark, YELLOW, r.node.Name, RC, r.node.PID, formatBytes(float64(r.node.Memory)))
+			if i == cursor {
+				text = "\033[7m" + text + RC // reverse video for the selection
+			}
+			fmt.Fprintln(scr, text)
+		}
+		fline(scr)
+		fmt.Fprintf(scr, "[↑/↓] move [←/→] collapse/expand [Enter] details [R] reload [Q] back (%d/%d)\n", cursor+1, len(rows))
+		scr.Flush()
+
+		var ev keyboard.KeyEvent
+		select {
+		case ev = <-keys:
+		case <-termResized:
+			continue
+		}
+
+		var node *procNode
+		if cursor < len(rows) {
+			node = rows[cursor].node
+		}
+
+		switch {
+		case ev.Key == keyboard.KeyEsc, ev.Key == keyboard.KeyCtrlC, ev.Rune == 'q', ev.Rune == 'Q':
+			return
+		case ev.Key == keyboard.KeyArrowUp:
+			cursor--
+		case ev.Key == keyboard.KeyArrowDown:
+			cursor++
+		case ev.Key == keyboard.KeyPgup:
+			cursor -= view
+		case ev.Key == keyboard.KeyPgdn:
+			cursor += view
+		case ev.Key == keyboard.KeyHome:
+			cursor = 0
+		case ev.Key == keyboard.KeyEnd:
+			cursor = len(rows) - 1
+		case ev.Key == keyboard.KeyArrowLeft && node != nil:
+			collapsed[node.PID] = true
+			rows = flattenProcTree(roots, collapsed)
+		case ev.Key == keyboard.KeyArrowRight && node != nil:
+			delete(collapsed, node.PID)
+			rows = flattenProcTree(roots, collapsed)
+		case ev.Key == keyboard.KeySpace && node != nil:
+			collapsed[node.PID] = !collapsed[node.PID]
+			rows = flattenProcTree(roots, collapsed)
+		case ev.Key == keyboard.KeyEnter && node != nil:
+			processDetail(scr, keys, node.PID)
+		case ev.Key == keyboard.KeyF5, ev.Rune == 'r', ev.Rune == 'R':
+			reload()
+		}
+	}
+}
+
+// processDetailLines collects everything we know about one process
+func processDetailLines(pid int32) []string {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return []string{RED + "Process is gone: " + err.Error() + RC}
+	}
+
+	// unavailable formats a value or the reason it is missing
+	unavailable := func(err error) string {
+		return RED + "n/a (" + err.Error() + ")" + RC
+	}
+	section := func(name string) string {
+		return YELLOW + "# " + name + ":" + RC
+	}
+
+	var lines []string
+	add := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+
+	name, _ := p.Name()
+	add("%s %s[%d]%s", section("Process"), YELLOW, pid, RC)
+	add(" Name     : %s", name)
+	if ppid, err := p.Ppid(); err == nil {
+		add(" Parent   : %d", ppid)
+	}
+	if usr, err := p.Username(); err == nil {
+		add(" User     : %s", usr)
+	}
+	if ms, err := p.CreateTime(); err == nil {
+		start := time.UnixMilli(ms)
+		add(" Started  : %s (%s ago)", start.Format("2006-01-02 15:04:05"), time.Since(start).Round(time.Second))
+	}
+	if cmd, err := p.Cmdline(); err == nil {
+		add(" Cmdline  : %s", cmd)
+	} else {
+		add(" Cmdline  : %s", unavailable(err))
+	}
+	if cwd, err := p.Cwd(); err == nil {
+		add(" Cwd      : %s", cwd)
+	} else {
+		add(" Cwd      : %s", unavailable(err))
+	}
+	add(" Cgroup   : %s", processCgroup(pid))
+
+	// Memory
+	lines = append(lines, section("Memory"))
+	if mi, err := p.MemoryInfo(); err == nil {
+		add(" RSS %s, VMS %s, Swap %s", formatBytes(float64(mi.RSS)), formatBytes(float64(mi.VMS)), formatBytes(float64(mi.Swap)))
+	}
+	add(" Maps     : %s", memoryMapsSummary(p))
+
+	// Open files
+	files, err := p.OpenFiles()
+	if err != nil {
+		add("%s %s", section("Open files"), unavailable(err))
+	} else {
+		add("%s %d", section("Open files"), len(files))
+		for _, f := range files {
+			add(" %4d %s", f.Fd, f.Path)
+		}
+	}
+
+	// Network connections
+	conns, err := p.Connections()
+	if err != nil {
+		add("%s %s", section("Connections"), unavailable(err))
+	} else {
+		add("%s %d", section("Connections"), len(conns))
+		for _, c := range conns {
+			add(" %-5s %-22s -> %-22s %s", socketProto(c.Type, c.Family), formatSockAddr(c.Laddr.IP, c.Laddr.Port), formatSockAddr(c.Raddr.IP, c.Raddr.Port), c.Status)
+		}
+	}
+
+	// Environment
+	env, err := p.Environ()
+	if err != nil {
+		add("%s %s", section("Environment"), unavailable(err))
+	} else {
+		add("%s %d", section("Environment"), len(env))
+		for _, e := range env {
+			add(" %s", e)
+		}
+	}
+
+	return lines
+}
+
+// processDetail shows the detail page of pid until Esc/Q.
+// It shares the screen and key channel of the tree view.
+func processDetail(scr *screen, keys <-chan keyboard.KeyEvent, pid int32) {
+	lines := processDetailLines(pid)
+	offset := 0
+
+	for {
+		_, height := termSize()
+		view := max(height-5, 1)
+		offset = max(0, min(offset, len(lines)-view))
+
+		fprintCommandTitle(scr, "Process Details")
+		fline(scr)
+		for i := offset; i < min(offset+view, len(lines)); i++ {
+			fmt.Fprintln(scr, lines[i])
+		}
+		fline(scr)
+		fmt.Fprintf(scr, "[↑/↓/PgUp/PgDn] scroll [R] reload [Q] back (%d/%d)\n", offset+1, len(lines))
+		scr.Flush()
+
+		var ev keyboard.KeyEvent
+		select {
+		case ev = <-keys:
+		case <-termResized:
+			continue
+		}
+
+		switch {
+		case ev.Key == keyboard.KeyEsc, ev.Key == keyboard.KeyCtrlC, ev.Key == keyboard.KeyEnter, ev.Rune == 'q', ev.Rune == 'Q':
+			return
+		case ev.Key == keyboard.KeyArrowUp:
+			offset--
+		case ev.Key == keyboard.KeyArrowDown:
+			offset++
+		case ev.Key == keyboard.KeyPgup:
+			offset -= view
+		case ev.Key == keyboard.KeyPgdn:
+			offset += view
+		case ev.Rune == 'r', ev.Rune == 'R':
+			lines = processDetailLines(pid)
+		}
+	}
+}
+
+// processCgroup returns the cgroup path(s) of pid (Linux only)
+func processCgroup(pid int32) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "n/a"
+	}
+
+	var groups []string
+	for _, l := range parseProcCgroupLines(string(data)) {
+		if l.Controllers == "" {
+			groups = append(groups, l.Path)
+		} else {
+			groups = append(groups, l.Controllers+"="+l.Path)
+		}
+	}
+	return strings.Join(groups, ", ")
+}
@@ -0,0 +1,214 @@
+// #############################################
+// CrunchyUtils - Monitor Recording & Replay
+//
+// This file contains:
+// - Recorder: writes every monitor snapshot as one
+//   JSON line into a gzip file (*.jsonl.gz)
+// - Replay: renders a recording through the normal
+//   monitor UI with pause, seek and speed controls
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/eiannone/keyboard"
+)
+
+// monitorRecorder appends snapshots to a compressed JSON-lines file
+type monitorRecorder struct {
+	path string
+	f    *os.File
+	gz   *gzip.Writer
+	enc  *json.Encoder
+}
+
+// newMonitorRecorder creates a new recording file.
+// An empty path picks a timestamped name in the data dir.
+func newMonitorRecorder(path string) (*monitorRecorder, error) {
+	if path == "" {
+		path = dataPath("monitor-" + time.Now().Format("20060102-150405") + ".jsonl.gz")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(f)
+	return &monitorRecorder{path: path, f: f, gz: gz, enc: json.NewEncoder(gz)}, nil
+}
+
+// Write stores one snapshot (Encode adds the newline)
+func (r *monitorRecorder) Write(snap sysSnapshot) error {
+	if err := r.enc.Encode(snap); err != nil {
+		return err
+	}
+	// Flush so a crash loses at most one tick
+	return r.gz.Flush()
+}
+
+// Close finishes the gzip stream and closes the file
+func (r *monitorRecorder) Close() error {
+	err := r.gz.Close()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// loadRecording reads all snapshots of a recording.
+// A truncated file (e.g. after a crash) returns what could be read.
+func loadRecording(path string) ([]sysSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var snaps []sysSnapshot
+	dec := json.NewDecoder(bufio.NewReader(gz))
+	for dec.More() {
+		var snap sysSnapshot
+		if err := dec.Decode(&snap); err != nil {
+			if len(snaps) > 0 {
+				break // keep the readable part
+			}
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+
+	if len(snaps) == 0 {
+		return nil, fmt.Errorf("recording %s is empty", path)
+	}
+
+	// Older recordings have no rates, derive them from the counters
+	for i := 1; i < len(snaps); i++ {
+		if snaps[i].NetRecvRate == 0 && snaps[i].NetSentRate == 0 {
+			snaps[i].NetRecvRate, snaps[i].NetSentRate = snaps[i].NetRates(snaps[i-1])
+		}
+	}
+	return snaps, nil
+}
+
+// replaySpeeds are the selectable playback speeds
+var replaySpeeds = []float64{0.25, 0.5, 1, 2, 4, 8, 16}
+
+// replayMonitor plays a recording in the system monitor UI.
+// Keys: Space pause, Left/Right seek 10 samples, Up/Down or +/- speed,
+// Home/End jump to start/end, Enter/Q quit.
+func replayMonitor(path string) {
+	snaps, err := loadRecording(path)
+	if err != nil {
+		printError(fmt.Sprintf("Replay failed: %v", err))
+		return
+	}
+
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		printError(fmt.Sprintf("Keyboard unavailable: %v", err))
+		return
+	}
+	defer keyboard.Close()
+
+	scr := newScreen()
+	defer scr.Close()
+
+	var (
+		idx     int
+		speed   = 2 // index into replaySpeeds, starts at 1x
+		paused  bool
+		elapsed time.Duration // virtual time since snaps[idx]
+		redraw  = true
+	)
+
+	// seek moves to sample i and clamps it
+	seek := func(i int) {
+		idx = max(0, min(i, len(snaps)-1))
+		elapsed = 0
+		redraw = true
+	}
+
+	const step = 100 * time.Millisecond
+	ticker := time.NewTicker(step)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ev := <-keys:
+			switch {
+			case isStopKey(ev):
+				scr.Close()
+				printInfo("Replay stopped")
+				return
+			case ev.Key == keyboard.KeySpace:
+				paused = !paused
+			case ev.Key == keyboard.KeyArrowLeft:
+				seek(idx - 10)
+			case ev.Key == keyboard.KeyArrowRight:
+				seek(idx + 10)
+			case ev.Key == keyboard.KeyHome:
+				seek(0)
+			case ev.Key == keyboard.KeyEnd:
+				seek(len(snaps) - 1)
+			case ev.Key == keyboard.KeyArrowUp, ev.Rune == '+':
+				speed = min(speed+1, len(replaySpeeds)-1)
+			case ev.Key == keyboard.KeyArrowDown, ev.Rune == '-':
+				speed = max(speed-1, 0)
+			}
+			redraw = true
+
+		case <-termResized:
+			redraw = true
+
+		case <-ticker.C:
+			if !paused && idx < len(snaps)-1 {
+				// Advance by the recorded gaps, scaled by speed
+				elapsed += time.Duration(float64(step) * replaySpeeds[speed])
+				for idx < len(snaps)-1 && elapsed >= snaps[idx+1].Time.Sub(snaps[idx].Time) {
+					elapsed -= snaps[idx+1].Time.Sub(snaps[idx].Time)
+					idx++
+					redraw = true
+				}
+			}
+		}
+
+		if !redraw {
+			continue
+		}
+		redraw = false
+
+		fprintCommandTitle(scr, "CrunchyUtils")
+		fprintCommandTitle(scr, "System Monitor - REPLAY")
+		fline(scr)
+		renderMonitor(scr, snaps[idx])
+
+		state := GREEN + "PLAY" + RC
+		if paused {
+			state = YELLOW + "PAUSED" + RC
+		} else if idx == len(snaps)-1 {
+			state = CYAN + "END" + RC
+		}
+		fmt.Fprintf(scr, "%s %d/%d %s %gx | [Space] [←/→] [+/-] [Q]\n",
+			state, idx+1, len(snaps), snaps[idx].Time.Format("2006-01-02 15:04:05"), replaySpeeds[speed])
+		scr.Flush()
+	}
+}
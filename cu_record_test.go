@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recordSnaps writes snaps to a new recording and returns its path
+func recordSnaps(t *testing.T, snaps []sysSnapshot) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rec.jsonl.gz")
+	r, err := newMonitorRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range snaps {
+		if err := r.Write(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testSnaps() []sysSnapshot {
+	start := time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC)
+	var snaps []sysSnapshot
+	for i := range 3 {
+		snaps = append(snaps, sysSnapshot{
+			Time:        start.Add(time.Duration(i) * time.Second),
+			CPUPercent:  float64(10 * i),
+			CPUCores:    4,
+			Processes:   []procInfo{{PID: 1234, Name: "go", CPU: 1.5, Memory: 1 << 20, Cgroup: "/user.slice"}},
+			RAMTotal:    8 << 30,
+			Disks:       []diskInfo{{Mount: "/", Total: 100, Used: 50, UsedPercent: 50}},
+			Net:         []netInfo{{Name: "eth0", BytesRecv: uint64(1000 * i), BytesSent: uint64(500 * i)}},
+			NetRecvRate: float64(1000 * i),
+			NetSentRate: float64(500 * i),
+			Cgroup:      cgroupInfo{Version: 2, Path: "/user.slice", MemoryLimit: 1 << 30},
+		})
+	}
+	return snaps
+}
+
+func TestRecordingRoundTrip(t *testing.T) {
+	want := testSnaps()
+	got, err := loadRecording(recordSnaps(t, want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestRecordingDerivesRates(t *testing.T) {
+	snaps := testSnaps()
+	for i := range snaps {
+		snaps[i].NetRecvRate, snaps[i].NetSentRate = 0, 0 // older recordings
+	}
+	got, err := loadRecording(recordSnaps(t, snaps))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[2].NetRecvRate != 1000 || got[2].NetSentRate != 500 {
+		t.Errorf("got rates %g/%g, want 1000/500", got[2].NetRecvRate, got[2].NetSentRate)
+	}
+}
+
+func TestRecordingTruncated(t *testing.T) {
+	path := recordSnaps(t, testSnaps())
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A crash loses the gzip trailer and part of the last line
+	if err := os.WriteFile(path, data[:len(data)-40], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 || len(got) > 3 {
+		t.Errorf("got %d snapshots from the truncated file", len(got))
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.jsonl.gz")
+	if _, err := loadRecording(recordSnaps(t, nil)); err == nil {
+		t.Error("empty recording: want an error")
+	}
+	if _, err := loadRecording(missing); err == nil {
+		t.Error("missing file: want an error")
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchTermSize keeps the terminal size up to date using SIGWINCH
+func watchTermSize() {
+	updateTermSize()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGWINCH)
+	go func() {
+		for range sig {
+			updateTermSize()
+		}
+	}()
+}
@@ -0,0 +1,15 @@
+package main
+
+import "time"
+
+// watchTermSize keeps the terminal size up to date.
+// Windows consoles have no SIGWINCH, so the size is polled.
+func watchTermSize() {
+	updateTermSize()
+
+	go func() {
+		for range time.Tick(500 * time.Millisecond) {
+			updateTermSize()
+		}
+	}()
+}
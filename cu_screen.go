@@ -0,0 +1,146 @@
+// #############################################
+// CrunchyUtils - Screen Renderer
+//
+// This file contains:
+// - screen: a flicker-free renderer for full-screen tools
+//
+// Tools write a whole frame into the screen (it is an
+// io.Writer) and call Flush. The screen lives in the
+// terminal's alternate buffer, moves the cursor to each
+// line that changed since the last frame and rewrites
+// only those. No `clear` process is spawned.
+//
+// Close restores the normal buffer and cursor. It is
+// meant to be deferred, so it also runs on panic, and
+// SIGINT/SIGTERM are caught to restore the terminal
+// before exiting.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// ANSI sequences used by the screen
+const (
+	ansiAltScreenOn  = "\033[?1049h"
+	ansiAltScreenOff = "\033[?1049l"
+	ansiCursorHide   = "\033[?25l"
+	ansiCursorShow   = "\033[?25h"
+	ansiClearAll     = "\033[2J"
+	ansiClearLine    = "\033[K"
+)
+
+// screen is a full-screen frame renderer
+type screen struct {
+	mu     sync.Mutex
+	frame  bytes.Buffer // frame being built
+	prev   []string     // lines currently on the terminal
+	cols   int          // terminal size the prev lines were drawn for
+	lines  int
+	sig    chan os.Signal
+	closed bool
+}
+
+// newScreen switches to the alternate buffer and hides the cursor
+func newScreen() *screen {
+	s := &screen{sig: make(chan os.Signal, 1)}
+	fmt.Print(ansiAltScreenOn + ansiCursorHide + ansiClearAll)
+
+	// Ctrl+C in cooked mode must not leave the alt buffer behind
+	signal.Notify(s.sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		if _, ok := <-s.sig; ok {
+			s.Close()
+			os.Exit(130)
+		}
+	}()
+	return s
+}
+
+// Write appends to the current frame
+func (s *screen) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.frame.Write(p)
+}
+
+// Flush draws the frame, only lines that changed are rewritten
+func (s *screen) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		s.frame.Reset()
+		return
+	}
+
+	lines := strings.Split(strings.TrimSuffix(s.frame.String(), "\n"), "\n")
+	s.frame.Reset()
+
+	var out strings.Builder
+
+	// After a resize the old content is garbage, start over
+	cols, rows := termSize()
+	if cols != s.cols || rows != s.lines {
+		s.cols, s.lines = cols, rows
+		s.prev = nil
+		out.WriteString(ansiClearAll)
+	}
+
+	// Lines must neither wrap nor scroll, or the positions break
+	if len(lines) > rows {
+		lines = lines[:rows]
+	}
+	for i := range lines {
+		lines[i] = truncateVisible(lines[i], cols-1)
+	}
+
+	for i, l := range lines {
+		if i < len(s.prev) && s.prev[i] == l {
+			continue
+		}
+		// Move to row i+1, col 1, write and clear the rest
+		fmt.Fprintf(&out, "\033[%d;1H%s%s%s", i+1, l, RC, ansiClearLine)
+	}
+	// Blank the lines the last frame had in addition
+	for i := len(lines); i < len(s.prev); i++ {
+		fmt.Fprintf(&out, "\033[%d;1H%s", i+1, ansiClearLine)
+	}
+
+	s.prev = lines
+	os.Stdout.WriteString(out.String())
+}
+
+// Invalidate forces the next Flush to redraw everything,
+// e.g. after something else printed to the terminal
+func (s *screen) Invalidate() {
+	s.mu.Lock()
+	s.prev = nil
+	s.mu.Unlock()
+	fmt.Print(ansiClearAll)
+}
+
+// Close leaves the alternate buffer and restores the cursor.
+// Safe to call more than once.
+func (s *screen) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	signal.Stop(s.sig)
+	close(s.sig)
+	fmt.Print(RC + ansiCursorShow + ansiAltScreenOff)
+}
@@ -0,0 +1,361 @@
+// #############################################
+// CrunchyUtils - Service Manager
+//
+// This file contains:
+// - Parsing of systemctl unit lists into structs
+// - The service manager screen (failed units first)
+// - Start/stop/restart/enable/disable of a unit
+// - Journal tail of a unit
+//
+// Everything goes through systemctl and journalctl,
+// like restartDisplay does. Linux with systemd only.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eiannone/keyboard"
+)
+
+// unitInfo is one systemd unit as listed by systemctl
+type unitInfo struct {
+	Name        string
+	Load        string // loaded, not-found, masked
+	Active      string // active, inactive, failed
+	Sub         string // running, exited, dead, failed
+	Enabled     string // enabled, disabled, static (from list-unit-files)
+	Description string
+}
+
+// Failed reports whether the unit is in the failed state
+func (u unitInfo) Failed() bool {
+	return u.Active == "failed" || u.Sub == "failed"
+}
+
+// serviceJournalLines is how many journal lines are shown
+const serviceJournalLines = 200
+
+// parseUnits parses "systemctl list-units --plain --no-legend".
+// Failed units can be marked with a leading bullet.
+func parseUnits(out string) []unitInfo {
+	var units []unitInfo
+	for _, l := range strings.Split(out, "\n") {
+		f := strings.Fields(l)
+		if len(f) > 0 && (f[0] == "●" || f[0] == "*") {
+			f = f[1:]
+		}
+		if len(f) < 4 {
+			continue
+		}
+		units = append(units, unitInfo{
+			Name:        f[0],
+			Load:        f[1],
+			Active:      f[2],
+			Sub:         f[3],
+			Description: strings.Join(f[4:], " "),
+		})
+	}
+	return units
+}
+
+// parseUnitFiles parses "systemctl list-unit-files --no-legend"
+// into unit name -> enablement state
+func parseUnitFiles(out string) map[string]string {
+	states := map[string]string{}
+	for _, l := range strings.Split(out, "\n") {
+		f := strings.Fields(l)
+		if len(f) < 2 {
+			continue
+		}
+		states[f[0]] = f[1]
+	}
+	return states
+}
+
+// unitEnabled looks up the enablement of a unit,
+// instances like "user@1000.service" use their template
+func unitEnabled(name string, states map[string]string) string {
+	if s, ok := states[name]; ok {
+		return s
+	}
+	if at := strings.Index(name, "@"); at >= 0 {
+		if dot := strings.LastIndex(name, "."); dot > at {
+			if s, ok := states[name[:at+1]+name[dot:]]; ok {
+				return s
+			}
+		}
+	}
+	return "-"
+}
+
+// sortUnits puts failed units first, then active ones, each by name
+func sortUnits(units []unitInfo) {
+	rank := func(u unitInfo) int {
+		switch {
+		case u.Failed():
+			return 0
+		case u.Active == "active":
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(units, func(i, j int) bool {
+		if ri, rj := rank(units[i]), rank(units[j]); ri != rj {
+			return ri < rj
+		}
+		return units[i].Name < units[j].Name
+	})
+}
+
+// getServices lists all service units with their enablement
+func getServices() ([]unitInfo, error) {
+	out, err := runCommand([]string{"systemctl", "list-units", "--type=service", "--all", "--plain", "--no-legend", "--no-pager"})
+	if err != nil {
+		return nil, err
+	}
+	units := parseUnits(out)
+
+	// Enablement is optional, the list is useful without it
+	if files, err := runCommand([]string{"systemctl", "list-unit-files", "--type=service", "--no-legend", "--no-pager"}); err == nil {
+		states := parseUnitFiles(files)
+		for i := range units {
+			units[i].Enabled = unitEnabled(units[i].Name, states)
+		}
+	}
+
+	sortUnits(units)
+	return units, nil
+}
+
+// serviceActions maps keys to systemctl verbs
+var serviceActions = map[rune]string{
+	's': "start",
+	't': "stop",
+	'r': "restart",
+	'e': "enable",
+	'd': "disable",
+}
+
+// serviceManager lists systemd services and runs actions on them.
+// Actions leave the full-screen view for the yes/no prompt.
+func serviceManager() {
+	if _, err := exec.LookPath("systemctl"); err != nil {
+		printError("The service manager needs systemd (systemctl not found)")
+		return
+	}
+
+	cursor := 0
+	for {
+		unit, action, ok := serviceList(&cursor)
+		if !ok {
+			return
+		}
+
+		if !yesNo(fmt.Sprintf("Do you want to %s %s?", action, unit)) {
+			continue
+		}
+		if _, err := runCommand([]string{"systemctl", action, unit}); err != nil {
+			printError(err.Error())
+		} else {
+			printSuccess(fmt.Sprintf("%s: %s done", unit, action))
+		}
+		pause()
+	}
+}
+
+// serviceList shows the unit list until the user picks an action
+// (returns unit and verb) or leaves (ok is false)
+func serviceList(cursor *int) (string, string, bool) {
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		printError(fmt.Sprintf("Keyboard unavailable: %v", err))
+		return "", "", false
+	}
+	defer keyboard.Close()
+
+	scr := newScreen()
+	defer scr.Close()
+
+	var (
+		units      []unitInfo
+		failedOnly bool
+		offset     int
+	)
+	reload := func() { units, err = getServices() }
+	reload()
+
+	for {
+		shown := units
+		if failedOnly {
+			shown = nil
+			for _, u := range units {
+				if u.Failed() {
+					shown = append(shown, u)
+				}
+			}
+		}
+		failed := 0
+		for _, u := range units {
+			if u.Failed() {
+				failed++
+			}
+		}
+
+		_, height := termSize()
+		view := max(height-7, 1) // title, summary, header, lines, footer
+		*cursor = max(0, min(*cursor, len(shown)-1))
+		if *cursor < offset {
+			offset = *cursor
+		}
+		if *cursor >= offset+view {
+			offset = *cursor - view + 1
+		}
+
+		fprintCommandTitle(scr, "Service Manager")
+		summary := fmt.Sprintf("%d services, %sno failed units%s", len(units), GREEN, RC)
+		if failed > 0 {
+			summary = fmt.Sprintf("%d services, %s%d FAILED%s", len(units), RED, failed, RC)
+		}
+		if failedOnly {
+			summary += " (showing failed only)"
+		}
+		fmt.Fprintln(scr, summary)
+		fline(scr)
+		if err != nil {
+			fmt.Fprintf(scr, "%sCould not list services: %v%s\n", RED, err, RC)
+		}
+		fmt.Fprintf(scr, "%s%-32s %-8s %-8s %-9s %s%s\n", YELLOW, "Unit", "Active", "Sub", "Enabled", "Description", RC)
+		for i := offset; i < min(offset+view, len(shown)); i++ {
+			u := shown[i]
+			text := fmt.Sprintf("%-32s %-8s %-8s %-9s %s", u.Name, u.Active, u.Sub, u.Enabled, u.Description)
+			switch {
+			case i == *cursor:
+				text = "\033[7m" + text + RC // reverse video for the selection
+			case u.Failed():
+				text = RED + text + RC
+			case u.Active == "active":
+				text = GREEN + text + RC
+			}
+			fmt.Fprintln(scr, text)
+		}
+		fline(scr)
+		fmt.Fprintf(scr, "[S]tart s[T]op [R]estart [E]nable [D]isable [J]ournal [F]ailed [F5] reload [Q] back\n")
+		scr.Flush()
+
+		var ev keyboard.KeyEvent
+		select {
+		case ev = <-keys:
+		case <-termResized:
+			continue
+		}
+
+		var unit string
+		if *cursor < len(shown) {
+			unit = shown[*cursor].Name
+		}
+
+		r := ev.Rune
+		if r >= 'A' && r <= 'Z' {
+			r += 'a' - 'A'
+		}
+		switch {
+		case ev.Key == keyboard.KeyEsc, ev.Key == keyboard.KeyCtrlC, r == 'q':
+			return "", "", false
+		case ev.Key == keyboard.KeyArrowUp:
+			*cursor--
+		case ev.Key == keyboard.KeyArrowDown:
+			*cursor++
+		case ev.Key == keyboard.KeyPgup:
+			*cursor -= view
+		case ev.Key == keyboard.KeyPgdn:
+			*cursor += view
+		case ev.Key == keyboard.KeyHome:
+			*cursor = 0
+		case ev.Key == keyboard.KeyEnd:
+			*cursor = len(shown) - 1
+		case ev.Key == keyboard.KeyF5:
+			reload()
+		case r == 'f':
+			failedOnly = !failedOnly
+			*cursor = 0
+		case (r == 'j' || ev.Key == keyboard.KeyEnter) && unit != "":
+			serviceJournal(scr, keys, unit)
+		case serviceActions[r] != "" && unit != "":
+			return unit, serviceActions[r], true
+		}
+	}
+}
+
+// serviceJournal tails the journal of unit until Esc/Q.
+// It shares the screen and key channel of the list and
+// reloads every two seconds, new lines appear at the bottom.
+func serviceJournal(scr *screen, keys <-chan keyboard.KeyEvent, unit string) {
+	read := func() []string {
+		out, err := runCommand([]string{"journalctl", "-u", unit, "-n", fmt.Sprint(serviceJournalLines), "--no-pager", "-o", "short-iso"})
+		if err != nil && out == "" {
+			return []string{RED + err.Error() + RC}
+		}
+		return strings.Split(out, "\n")
+	}
+	lines := read()
+	follow := true // stick to the end while new lines arrive
+	offset := 0
+
+	refresh := time.NewTicker(2 * time.Second)
+	defer refresh.Stop()
+
+	for {
+		_, height := termSize()
+		view := max(height-5, 1)
+		if follow {
+			offset = len(lines) - view
+		}
+		offset = max(0, min(offset, len(lines)-view))
+
+		fprintCommandTitle(scr, "Journal: "+unit)
+		fline(scr)
+		for i := offset; i < min(offset+view, len(lines)); i++ {
+			fmt.Fprintln(scr, lines[i])
+		}
+		fline(scr)
+		mode := "following"
+		if !follow {
+			mode = "paused, [End] to follow"
+		}
+		fmt.Fprintf(scr, "[↑/↓/PgUp/PgDn] scroll [Q] back (%s)\n", mode)
+		scr.Flush()
+
+		var ev keyboard.KeyEvent
+		select {
+		case ev = <-keys:
+		case <-termResized:
+			continue
+		case <-refresh.C:
+			lines = read()
+			continue
+		}
+
+		switch {
+		case ev.Key == keyboard.KeyEsc, ev.Key == keyboard.KeyCtrlC, ev.Rune == 'q', ev.Rune == 'Q':
+			return
+		case ev.Key == keyboard.KeyArrowUp:
+			offset, follow = offset-1, false
+		case ev.Key == keyboard.KeyArrowDown:
+			offset++
+		case ev.Key == keyboard.KeyPgup:
+			offset, follow = offset-view, false
+		case ev.Key == keyboard.KeyPgdn:
+			offset += view
+		case ev.Key == keyboard.KeyEnd:
+			follow = true
+		}
+	}
+}
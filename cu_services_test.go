@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseUnits(t *testing.T) {
+	units := parseUnits(readTestdata(t, "systemctl-list-units.txt"))
+	if len(units) != 10 {
+		t.Fatalf("got %d units, want 10", len(units))
+	}
+
+	want := map[string]unitInfo{
+		"accounts-daemon.service": {Name: "accounts-daemon.service", Load: "loaded", Active: "active", Sub: "running", Description: "Accounts Service"},
+		"auditd.service":          {Name: "auditd.service", Load: "not-found", Active: "inactive", Sub: "dead", Description: "auditd.service"},
+		"fwupd-refresh.service":   {Name: "fwupd-refresh.service", Load: "loaded", Active: "failed", Sub: "failed", Description: "Refresh fwupd metadata and update motd"},
+	}
+	for _, u := range units {
+		if w, ok := want[u.Name]; ok && u != w {
+			t.Errorf("got %+v, want %+v", u, w)
+		}
+	}
+}
+
+func TestParseUnitFiles(t *testing.T) {
+	states := parseUnitFiles(readTestdata(t, "systemctl-list-unit-files.txt"))
+
+	tests := []struct {
+		unit, want string
+	}{
+		{"ssh.service", "enabled"},
+		{"nginx.service", "disabled"},
+		{"gdm.service", "static"},
+		{"user@1000.service", "static"}, // from the template
+		{"missing.service", "-"},
+	}
+	for _, tt := range tests {
+		if got := unitEnabled(tt.unit, states); got != tt.want {
+			t.Errorf("unitEnabled(%q) = %q, want %q", tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestSortUnits(t *testing.T) {
+	units := parseUnits(readTestdata(t, "systemctl-list-units.txt"))
+	sortUnits(units)
+
+	var order []string
+	for _, u := range units[:3] {
+		order = append(order, u.Name)
+	}
+	want := []string{"fwupd-refresh.service", "nginx.service", "accounts-daemon.service"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("first units are %v, want %v", order, want)
+		}
+	}
+}
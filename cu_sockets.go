@@ -0,0 +1,273 @@
+// #############################################
+// CrunchyUtils - Network Sockets
+//
+// This file contains:
+// - Listing of TCP/UDP sockets with owning process
+// - Filtering by port, address, process, PID or state
+// - The sockets viewer screen
+//
+// Answers "what is holding port 8080?": open the
+// viewer, press / and type 8080.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eiannone/keyboard"
+	psnet "github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// sockInfo is one socket with its owner
+type sockInfo struct {
+	Proto      string
+	LocalIP    string
+	LocalPort  uint32
+	RemoteIP   string
+	RemotePort uint32
+	State      string
+	PID        int32
+	Process    string
+}
+
+// Listening reports whether the socket waits for connections
+func (s sockInfo) Listening() bool {
+	if strings.HasPrefix(s.Proto, "udp") {
+		// UDP has no state, unconnected sockets count as listening
+		return s.RemotePort == 0
+	}
+	return s.State == "LISTEN"
+}
+
+// getSockets returns all TCP and UDP sockets sorted by local port
+func getSockets() ([]sockInfo, error) {
+	conns, err := psnet.Connections("inet")
+	if err != nil {
+		return nil, err
+	}
+
+	// Many sockets share a process, look names up once
+	names := map[int32]string{}
+	procName := func(pid int32) string {
+		if pid == 0 {
+			return "-"
+		}
+		if n, ok := names[pid]; ok {
+			return n
+		}
+		n := "?"
+		if p, err := process.NewProcess(pid); err == nil {
+			if name, err := p.Name(); err == nil {
+				n = name
+			}
+		}
+		names[pid] = n
+		return n
+	}
+
+	socks := make([]sockInfo, 0, len(conns))
+	for _, c := range conns {
+		state := c.Status
+		if state == "NONE" || state == "" {
+			state = "-"
+		}
+		socks = append(socks, sockInfo{
+			Proto:      socketProto(c.Type, c.Family),
+			LocalIP:    c.Laddr.IP,
+			LocalPort:  c.Laddr.Port,
+			RemoteIP:   c.Raddr.IP,
+			RemotePort: c.Raddr.Port,
+			State:      state,
+			PID:        c.Pid,
+			Process:    procName(c.Pid),
+		})
+	}
+
+	sort.Slice(socks, func(i, j int) bool {
+		if socks[i].LocalPort != socks[j].LocalPort {
+			return socks[i].LocalPort < socks[j].LocalPort
+		}
+		return socks[i].Proto < socks[j].Proto
+	})
+	return socks, nil
+}
+
+// matchSocket checks one socket against a filter.
+// The filter is a list of words that all have to match. A word can be
+// "port:8080", "addr:10.0.0.5", "pid:123", "proc:nginx", "state:listen"
+// or plain text, which matches any of them.
+func matchSocket(s sockInfo, filter string) bool {
+	for _, word := range strings.Fields(strings.ToLower(filter)) {
+		key, val, found := strings.Cut(word, ":")
+		if !found {
+			key, val = "", word
+		}
+
+		port := func() bool {
+			p, err := strconv.ParseUint(val, 10, 32)
+			return err == nil && (uint32(p) == s.LocalPort || uint32(p) == s.RemotePort)
+		}
+		addr := func() bool { return strings.Contains(s.LocalIP, val) || strings.Contains(s.RemoteIP, val) }
+		pid := func() bool { return val == strconv.Itoa(int(s.PID)) }
+		proc := func() bool { return strings.Contains(strings.ToLower(s.Process), val) }
+		state := func() bool { return strings.Contains(strings.ToLower(s.State), val) || strings.HasPrefix(s.Proto, val) }
+
+		var ok bool
+		switch key {
+		case "port":
+			ok = port()
+		case "addr":
+			ok = addr()
+		case "pid":
+			ok = pid()
+		case "proc":
+			ok = proc()
+		case "state":
+			ok = state()
+		default:
+			ok = port() || addr() || pid() || proc() || state()
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// socketViews are the quick filters of the viewer
+var socketViews = []struct {
+	name  string
+	match func(sockInfo) bool
+}{
+	{"Listening", sockInfo.Listening},
+	{"Established", func(s sockInfo) bool { return s.State == "ESTABLISHED" }},
+	{"All", func(sockInfo) bool { return true }},
+}
+
+// socketViewer lists sockets and their owners.
+// Keys: Tab switch view, / edit filter, Up/Down/PgUp/PgDn scroll,
+// R reload, Esc/Q leave.
+func socketViewer() {
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		printError(fmt.Sprintf("Keyboard unavailable: %v", err))
+		return
+	}
+	defer keyboard.Close()
+
+	scr := newScreen()
+	defer scr.Close()
+
+	var (
+		socks   []sockInfo
+		view    int
+		filter  string
+		editing bool // typing into the filter
+		offset  int
+	)
+	reload := func() { socks, err = getSockets() }
+	reload()
+
+	for {
+		// Apply view and filter
+		var shown []sockInfo
+		for _, s := range socks {
+			if socketViews[view].match(s) && matchSocket(s, filter) {
+				shown = append(shown, s)
+			}
+		}
+
+		_, height := termSize()
+		rows := max(height-7, 1) // title, tabs, header, lines, footer
+		offset = max(0, min(offset, len(shown)-rows))
+
+		fprintCommandTitle(scr, "Network Sockets")
+		var tabs []string
+		for i, v := range socketViews {
+			if i == view {
+				tabs = append(tabs, "\033[7m "+v.name+" "+RC)
+			} else {
+				tabs = append(tabs, " "+v.name+" ")
+			}
+		}
+		cursor := ""
+		if editing {
+			cursor = "_"
+		}
+		fmt.Fprintf(scr, "%s  Filter: %s%s%s%s\n", strings.Join(tabs, ""), YELLOW, filter, cursor, RC)
+		fline(scr)
+		if err != nil {
+			fmt.Fprintf(scr, "%sCould not read sockets: %v%s\n", RED, err, RC)
+		}
+		fmt.Fprintf(scr, "%s%-5s %-24s %-24s %-11s %7s %s%s\n", YELLOW, "Proto", "Local", "Remote", "State", "PID", "Process", RC)
+		for i := offset; i < min(offset+rows, len(shown)); i++ {
+			s := shown[i]
+			remote := "-"
+			if s.RemotePort != 0 {
+				remote = formatSockAddr(s.RemoteIP, s.RemotePort)
+			}
+			fmt.Fprintf(scr, "%-5s %-24s %-24s %-11s %7d %s\n",
+				s.Proto, formatSockAddr(s.LocalIP, s.LocalPort), remote, s.State, s.PID, s.Process)
+		}
+		fline(scr)
+		if editing {
+			fmt.Fprintf(scr, "Type filter (port:, addr:, pid:, proc:, state:), [Enter] done, [Esc] clear\n")
+		} else {
+			fmt.Fprintf(scr, "[Tab] view [/] filter [↑/↓] scroll [R] reload [Q] back (%d sockets)\n", len(shown))
+		}
+		scr.Flush()
+
+		var ev keyboard.KeyEvent
+		select {
+		case ev = <-keys:
+		case <-termResized:
+			continue
+		}
+
+		if editing {
+			switch {
+			case ev.Key == keyboard.KeyEnter:
+				editing = false
+			case ev.Key == keyboard.KeyEsc:
+				filter, editing = "", false
+			case ev.Key == keyboard.KeyBackspace, ev.Key == keyboard.KeyBackspace2:
+				_, size := utf8.DecodeLastRuneInString(filter)
+				filter = filter[:len(filter)-size]
+			case ev.Key == keyboard.KeySpace:
+				filter += " "
+			case ev.Rune != 0:
+				filter += string(ev.Rune)
+			}
+			offset = 0
+			continue
+		}
+
+		switch {
+		case ev.Key == keyboard.KeyEsc, ev.Key == keyboard.KeyCtrlC, ev.Rune == 'q', ev.Rune == 'Q':
+			return
+		case ev.Key == keyboard.KeyTab:
+			view = (view + 1) % len(socketViews)
+			offset = 0
+		case ev.Rune == '/':
+			editing = true
+		case ev.Key == keyboard.KeyArrowUp:
+			offset--
+		case ev.Key == keyboard.KeyArrowDown:
+			offset++
+		case ev.Key == keyboard.KeyPgup:
+			offset -= rows
+		case ev.Key == keyboard.KeyPgdn:
+			offset += rows
+		case ev.Key == keyboard.KeyF5, ev.Rune == 'r', ev.Rune == 'R':
+			reload()
+		}
+	}
+}
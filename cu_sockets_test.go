@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestMatchSocket(t *testing.T) {
+	nginx := sockInfo{Proto: "tcp", LocalIP: "0.0.0.0", LocalPort: 80, State: "LISTEN", PID: 812, Process: "nginx"}
+	ssh := sockInfo{Proto: "tcp6", LocalIP: "2001:db8::5", LocalPort: 22, RemoteIP: "2001:db8::9", RemotePort: 50122, State: "ESTABLISHED", PID: 1044, Process: "sshd"}
+	dns := sockInfo{Proto: "udp", LocalIP: "127.0.0.53", LocalPort: 53, PID: 501, Process: "systemd-resolved"}
+
+	tests := []struct {
+		filter string
+		s      sockInfo
+		want   bool
+	}{
+		{"", nginx, true},
+		// Ports, local and remote
+		{"port:80", nginx, true},
+		{"port:8080", nginx, false},
+		{"port:50122", ssh, true},
+		{"port:abc", nginx, false},
+		{"53", dns, true},
+		// Addresses
+		{"addr:127.0.0", dns, true},
+		{"addr:2001:db8::9", ssh, true},
+		{"addr:10.0.0.1", nginx, false},
+		{"127.0.0.53", dns, true},
+		// PIDs and process names, case-insensitive
+		{"pid:812", nginx, true},
+		{"pid:81", nginx, false},
+		{"proc:NGINX", nginx, true},
+		{"proc:resolved", dns, true},
+		{"proc:ssh", nginx, false},
+		{"sshd", ssh, true},
+		// States and protocols
+		{"state:listen", nginx, true},
+		{"state:listen", ssh, false},
+		{"state:estab", ssh, true},
+		{"state:udp", dns, true},
+		{"tcp6", ssh, true},
+		// All words have to match
+		{"proc:nginx port:80", nginx, true},
+		{"proc:nginx port:443", nginx, false},
+		{"  sshd   state:established ", ssh, true},
+	}
+	for _, tt := range tests {
+		if got := matchSocket(tt.s, tt.filter); got != tt.want {
+			t.Errorf("%q on %s:%d (%s): got %v, want %v", tt.filter, tt.s.LocalIP, tt.s.LocalPort, tt.s.Process, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// #############################################
+// CrunchyUtils - Status Subcommand
+//
+// This file contains:
+// - The headless "status" subcommand
+//
+// Usage:
+//   crunchyutils status          (readable text)
+//   crunchyutils status --json   (for scripts)
+//
+// Prints the banner and monitor data once and
+// exits. No splash, admin check or keyboard.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// statusTopProcs is how many processes the status lists
+const statusTopProcs = 10
+
+// statusReport is the output of "status --json".
+// The snapshot fields are inlined.
+type statusReport struct {
+	Version  string `json:"version"`
+	Hostname string `json:"hostname"`
+	OS       string `json:"os"`
+	sysSnapshot
+}
+
+// statusCmd runs the status subcommand and returns the exit code
+func statusCmd(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print JSON instead of text")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// Network counters are read again after the one second
+	// CPU measurement, which gives us transfer rates
+	prev := sysSnapshot{Time: time.Now(), Net: getNetCounters()}
+	report := statusReport{Version: CU_VERSION, OS: goos, sysSnapshot: takeSnapshot()}
+	report.NetRecvRate, report.NetSentRate = report.NetRates(prev)
+	report.Hostname, _ = os.Hostname()
+	if len(report.Processes) > statusTopProcs {
+		report.Processes = report.Processes[:statusTopProcs]
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			eprintError(err.Error())
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Printf("CrunchyUtils %s on %s (%s)\n", report.Version, report.Hostname, report.OS)
+	fmt.Printf("Uptime   : %s\n", time.Duration(report.Uptime)*time.Second)
+	fmt.Printf("CPU      : %.1f%% of %d cores\n", report.EffectiveCPUPercent(), report.CPUCores)
+	total, used, _ := report.EffectiveRAM()
+	fmt.Printf("RAM      : %.1f%% (%s of %s)\n", report.RAMPercent(), formatBytes(float64(used)), formatBytes(float64(total)))
+	for _, d := range report.Disks {
+		fmt.Printf("Disk     : %s %.1f%% (%s of %s)\n", d.Mount, d.UsedPercent, formatBytes(float64(d.Used)), formatBytes(float64(d.Total)))
+	}
+	fmt.Printf("Network  : %s/s down, %s/s up\n", formatBytes(report.NetRecvRate), formatBytes(report.NetSentRate))
+	for _, p := range report.Processes {
+		fmt.Printf("Process  : %-7d %-20s %5.1f%% %s\n", p.PID, p.Name, p.CPU, formatBytes(float64(p.Memory)))
+	}
+	return 0
+}
@@ -0,0 +1,260 @@
+// #############################################
+// CrunchyUtils - Stopwatch
+//
+// This file contains:
+// - The stopwatch with laps, pause/resume and reset
+// - Export of the laps to CSV or JSON
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eiannone/keyboard"
+)
+
+// stopwatchLap is one recorded lap
+type stopwatchLap struct {
+	Number int           `json:"lap"`
+	Lap    time.Duration `json:"-"` // time since the previous lap
+	Split  time.Duration `json:"-"` // time since the start
+}
+
+// MarshalJSON writes the times in seconds, easier for scripts than nanoseconds
+func (l stopwatchLap) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Number int     `json:"lap"`
+		Lap    float64 `json:"lap_seconds"`
+		Split  float64 `json:"split_seconds"`
+	}{l.Number, l.Lap.Seconds(), l.Split.Seconds()})
+}
+
+// formatLapTime formats a duration as HH:MM:SS.cc
+func formatLapTime(d time.Duration) string {
+	cs := int(d / (10 * time.Millisecond))
+	return fmt.Sprintf("%s.%02d", formatTime(cs/100), cs%100)
+}
+
+// lapExtremes returns the index of the fastest and slowest lap, -1 if fewer than two
+func lapExtremes(laps []stopwatchLap) (int, int) {
+	if len(laps) < 2 {
+		return -1, -1
+	}
+	fast, slow := 0, 0
+	for i, l := range laps {
+		if l.Lap < laps[fast].Lap {
+			fast = i
+		}
+		if l.Lap > laps[slow].Lap {
+			slow = i
+		}
+	}
+	return fast, slow
+}
+
+// stopwatch runs a stopwatch with laps and offers to save them afterwards
+func stopwatch() {
+	laps, total, ok := stopwatchScreen()
+	if !ok {
+		return
+	}
+	printInfo("Stopwatch stopped: " + formatLapTime(total))
+	if len(laps) == 0 {
+		return
+	}
+
+	fast, slow := lapExtremes(laps)
+	fmt.Printf("%s%4s  %-12s  %-12s%s\n", YELLOW, "Lap", "Lap time", "Split", RC)
+	for i, l := range laps {
+		fmt.Println(lapRow(l, i == fast, i == slow))
+	}
+	saveLaps(laps)
+}
+
+// lapRow formats one line of the lap table
+func lapRow(l stopwatchLap, fastest, slowest bool) string {
+	row := fmt.Sprintf("%4d  %-12s  %-12s", l.Number, formatLapTime(l.Lap), formatLapTime(l.Split))
+	switch {
+	case fastest:
+		return GREEN + row + " fastest" + RC
+	case slowest:
+		return RED + row + " slowest" + RC
+	}
+	return row
+}
+
+// stopwatchScreen runs the full-screen stopwatch.
+// Keys: L/Space lap, P pause/resume, R reset, F big digits, Enter/Esc/Q stop.
+func stopwatchScreen() ([]stopwatchLap, time.Duration, bool) {
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		printError(fmt.Sprintf("Keyboard unavailable: %v", err))
+		return nil, 0, false
+	}
+	defer keyboard.Close()
+
+	scr := newScreen()
+	defer scr.Close()
+
+	var (
+		laps    []stopwatchLap
+		stored  time.Duration              // time before the last resume
+		started = time.Now()               // zero while paused
+		big     = config.Display.BigDigits // [F] toggles it for this screen only
+	)
+	elapsed := func() time.Duration {
+		if started.IsZero() {
+			return stored
+		}
+		return stored + time.Since(started) // monotonic clock
+	}
+
+	ticker := time.NewTicker(50 * time.Millisecond) // smooth hundredths
+	defer ticker.Stop()
+
+	for {
+		now := elapsed()
+		current := now
+		if len(laps) > 0 {
+			current = now - laps[len(laps)-1].Split
+		}
+
+		state := GREEN
+		if started.IsZero() {
+			state = YELLOW
+		}
+		keyHelp := "[L/Space] lap [P] pause/resume [R] reset [F] big digits [Enter] stop"
+
+		// Big digits drop the hundredths if the terminal is too narrow
+		rows := bigText(formatLapTime(now))
+		if !bigFits(rows) {
+			rows = bigText(formatTime(int(now / time.Second)))
+		}
+		if big && bigFits(rows) {
+			info := []string{fmt.Sprintf("Lap %d: %s", len(laps)+1, formatLapTime(current))}
+			if started.IsZero() {
+				info = append(info, YELLOW+"PAUSED"+RC)
+			}
+			fprintBigFrame(scr, "Stopwatch", bigClock(rows, state, -1, info...), keyHelp)
+		} else {
+			fprintCommandTitle(scr, "Stopwatch")
+			fline(scr)
+			fmt.Fprintf(scr, "\n   %s%s%s   Lap %d: %s\n\n", state, formatLapTime(now), RC, len(laps)+1, formatLapTime(current))
+			if started.IsZero() {
+				fmt.Fprintf(scr, "   %sPAUSED%s\n", YELLOW, RC)
+			}
+
+			// Newest laps first, as many as fit
+			if len(laps) > 0 {
+				_, height := termSize()
+				rows := max(height-11, 1)
+				fast, slow := lapExtremes(laps)
+				fmt.Fprintf(scr, "%s%4s  %-12s  %-12s%s\n", YELLOW, "Lap", "Lap time", "Split", RC)
+				for i := len(laps) - 1; i >= max(0, len(laps)-rows); i-- {
+					fmt.Fprintln(scr, lapRow(laps[i], i == fast, i == slow))
+				}
+			}
+			fline(scr)
+			fmt.Fprintf(scr, "%s\n", keyHelp)
+		}
+		scr.Flush()
+
+		select {
+		case <-ticker.C:
+		case <-termResized:
+		case ev := <-keys:
+			switch {
+			case isStopKey(ev):
+				return laps, elapsed(), true
+			case ev.Rune == 'l', ev.Rune == 'L', ev.Key == keyboard.KeySpace:
+				if !started.IsZero() {
+					laps = append(laps, stopwatchLap{Number: len(laps) + 1, Lap: current, Split: now})
+				}
+			case ev.Rune == 'p', ev.Rune == 'P':
+				if started.IsZero() {
+					started = time.Now()
+				} else {
+					stored, started = elapsed(), time.Time{}
+				}
+			case ev.Rune == 'r', ev.Rune == 'R':
+				laps, stored = nil, 0
+				if !started.IsZero() {
+					started = time.Now()
+				}
+			case ev.Rune == 'f', ev.Rune == 'F':
+				big = !big
+			}
+		}
+	}
+}
+
+// saveLaps asks for a format and writes the laps next to the config
+func saveLaps(laps []stopwatchLap) {
+	fmt.Printf("Save laps? [C]SV, [J]SON or [Enter] to skip%s", PROMPT)
+	answer, _ := reader.ReadString('\n')
+
+	var (
+		ext   string
+		write func(io.Writer, []stopwatchLap) error
+	)
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "c", "csv":
+		ext, write = "csv", writeLapsCSV
+	case "j", "json":
+		ext, write = "json", writeLapsJSON
+	default:
+		return
+	}
+
+	path := dataPath(fmt.Sprintf("laps-%s.%s", time.Now().Format("20060102-150405"), ext))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		printError(err.Error())
+		return
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		printError(err.Error())
+		return
+	}
+	defer f.Close()
+
+	if err := write(f, laps); err != nil {
+		printError(fmt.Sprintf("Saving laps failed: %v", err))
+		return
+	}
+	printSuccess("Laps saved: " + path)
+}
+
+// writeLapsCSV writes one row per lap, times readable and in seconds
+func writeLapsCSV(w io.Writer, laps []stopwatchLap) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"lap", "lap_time", "split_time", "lap_seconds", "split_seconds"})
+	for _, l := range laps {
+		cw.Write([]string{
+			fmt.Sprint(l.Number),
+			formatLapTime(l.Lap),
+			formatLapTime(l.Split),
+			fmt.Sprintf("%.3f", l.Lap.Seconds()),
+			fmt.Sprintf("%.3f", l.Split.Seconds()),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeLapsJSON writes the laps as an indented JSON array
+func writeLapsJSON(w io.Writer, laps []stopwatchLap) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(laps)
+}
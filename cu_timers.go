@@ -0,0 +1,221 @@
+// #############################################
+// CrunchyUtils - Background Timers
+//
+// This file contains:
+// - The timer manager: named countdowns, stopwatches
+//   and alarms that run while other tools are open
+// - The timer strip shown in the banner
+// - Listing and cancelling from the Timer menu
+//
+// Timers live in memory for the current session,
+// saved alarms are in cu_alarms.go.
+// When one is due, notifyAlarm fires with its name,
+// whatever screen is open.
+//
+// Author: Knuspii (M)
+// #############################################
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kinds of background timers
+const (
+	timerCountdown = "countdown"
+	timerStopwatch = "stopwatch"
+)
+
+// bgTimer is one named background timer
+type bgTimer struct {
+	ID      int
+	Name    string
+	Kind    string
+	Started time.Time
+	Due     time.Time // zero for stopwatches
+	fire    *time.Timer
+}
+
+// Status describes the timer for lists, e.g. "00:04:12 left"
+func (t *bgTimer) Status(now time.Time) string {
+	if t.Kind == timerStopwatch {
+		return formatTime(int(now.Sub(t.Started).Seconds())) + " elapsed"
+	}
+	left := int(t.Due.Sub(now).Seconds() + 0.999) // round up, 0 only when due
+	return fmt.Sprintf("%s left (at %s)", formatTime(max(left, 0)), t.Due.Format("15:04:05"))
+}
+
+// DueText describes the timer without a running count, e.g. "at 14:05:00".
+// The banner is only redrawn on key presses, a countdown there goes stale.
+func (t *bgTimer) DueText() string {
+	if t.Kind == timerStopwatch {
+		return "running since " + t.Started.Format("15:04:05")
+	}
+	return "at " + t.Due.Format("15:04:05")
+}
+
+var (
+	timersMu      sync.Mutex
+	timers        []*bgTimer
+	timerNextID   = 1
+	timersChanged = make(chan struct{}, 1) // signalled when a timer is added, fires or is cancelled
+)
+
+// signalTimersChanged wakes up the menu so the strip is redrawn
+func signalTimersChanged() {
+	select {
+	case timersChanged <- struct{}{}:
+	default:
+	}
+}
+
+// addTimer starts a background timer. Stopwatches have no due time.
+func addTimer(name, kind string, due time.Time) *bgTimer {
+	timersMu.Lock()
+	defer timersMu.Unlock()
+
+	t := &bgTimer{ID: timerNextID, Name: name, Kind: kind, Started: time.Now(), Due: due.Round(0)}
+	timerNextID++
+	if kind != timerStopwatch {
+		t.fire = time.AfterFunc(time.Until(due), func() { finishTimer(t) })
+		timerPollOnce.Do(func() { go pollTimers() })
+	}
+	timers = append(timers, t)
+	signalTimersChanged()
+	return t
+}
+
+// timerPollOnce starts pollTimers with the first timer
+var timerPollOnce sync.Once
+
+// pollTimers fires timers whose wall-clock deadline passed. AfterFunc
+// runs on the monotonic clock, which stands still while the machine
+// is suspended, so after a resume it would fire late.
+func pollTimers() {
+	for now := range time.Tick(time.Second) {
+		for _, t := range dueTimers(now) {
+			go finishTimer(t)
+		}
+	}
+}
+
+// dueTimers returns the countdowns due at now by the wall clock
+func dueTimers(now time.Time) []*bgTimer {
+	now = now.Round(0) // compare wall clocks only
+	timersMu.Lock()
+	defer timersMu.Unlock()
+
+	var due []*bgTimer
+	for _, t := range timers {
+		if !t.Due.IsZero() && !now.Before(t.Due) {
+			due = append(due, t)
+		}
+	}
+	return due
+}
+
+// finishTimer removes a due timer and notifies the user
+func finishTimer(t *bgTimer) {
+	if !removeTimer(t.ID) {
+		return // cancelled or fired by the other clock in the meantime
+	}
+	notifyAlarm(notification{Tool: "timer", Title: "Timer finished", Body: t.Name + " finished"})
+}
+
+// cancelTimer stops and removes a timer, returns it or nil if unknown
+func cancelTimer(id int) *bgTimer {
+	timersMu.Lock()
+	var found *bgTimer
+	for _, t := range timers {
+		if t.ID == id {
+			found = t
+		}
+	}
+	timersMu.Unlock()
+
+	if found == nil || !removeTimer(id) {
+		return nil
+	}
+	if found.fire != nil {
+		found.fire.Stop()
+	}
+	return found
+}
+
+// removeTimer drops a timer from the list, false if it was not there
+func removeTimer(id int) bool {
+	timersMu.Lock()
+	defer timersMu.Unlock()
+
+	for i, t := range timers {
+		if t.ID == id {
+			timers = append(timers[:i], timers[i+1:]...)
+			signalTimersChanged()
+			return true
+		}
+	}
+	return false
+}
+
+// listTimers returns the running timers, next due first, stopwatches last
+func listTimers() []*bgTimer {
+	timersMu.Lock()
+	list := append([]*bgTimer(nil), timers...)
+	timersMu.Unlock()
+
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Due.IsZero() != b.Due.IsZero() {
+			return !a.Due.IsZero()
+		}
+		return a.Due.Before(b.Due)
+	})
+	return list
+}
+
+// timerStrip is the banner line for the running timers and alarms,
+// "" if there are none. A ringing alarm comes first.
+func timerStrip() string {
+	if label := ringingAlarm(); label != "" {
+		return fmt.Sprintf("%sRINGING %s%s [A] stop [Z] snooze", RED, label, RC)
+	}
+
+	list := listTimers()
+	label, next := nextAlarm()
+
+	// The next alarm shows if no timer is due before it
+	if !next.IsZero() && (len(list) == 0 || list[0].Due.IsZero() || next.Before(list[0].Due)) {
+		strip := fmt.Sprintf("%s%s%s at %s", YELLOW, label, RC, next.Format("Mon 15:04"))
+		if len(list) > 0 {
+			strip += fmt.Sprintf(" (+%d timers)", len(list))
+		}
+		return strip
+	}
+	if len(list) == 0 {
+		return ""
+	}
+
+	t := list[0]
+	strip := fmt.Sprintf("%s%s%s %s", YELLOW, t.Name, RC, t.DueText())
+	if len(list) > 1 {
+		strip += fmt.Sprintf(" (+%d more)", len(list)-1)
+	}
+	return strip
+}
+
+// printTimers lists the running timers with their IDs
+func printTimers() {
+	list := listTimers()
+	if len(list) == 0 {
+		fmt.Printf(" No background timers running\n")
+		return
+	}
+	now := time.Now()
+	for _, t := range list {
+		fmt.Printf(" #%-3d %s%-20s%s %-9s %s\n", t.ID, YELLOW, t.Name, RC, t.Kind, t.Status(now))
+	}
+}
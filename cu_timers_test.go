@@ -0,0 +1,32 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDueTimersWallClock(t *testing.T) {
+	now := time.Now()
+	soon := addTimer("Tea", timerCountdown, now.Add(time.Hour))
+	later := addTimer("Laundry", timerCountdown, now.Add(3*time.Hour))
+	watch := addTimer("Run", timerStopwatch, time.Time{})
+	defer func() {
+		for _, tm := range []*bgTimer{soon, later, watch} {
+			cancelTimer(tm.ID)
+		}
+	}()
+
+	if due := dueTimers(now); slices.Contains(due, soon) || slices.Contains(due, later) {
+		t.Errorf("nothing due yet, got %d timers", len(due))
+	}
+
+	// After a two hour suspend the monotonic clock is behind, the
+	// wall clock is not. A time without monotonic reading is what
+	// the system clock shows after the resume.
+	resumed := now.Add(2 * time.Hour).Round(0)
+	due := dueTimers(resumed)
+	if !slices.Contains(due, soon) || slices.Contains(due, later) || slices.Contains(due, watch) {
+		t.Errorf("after resume: got %v, want only Tea", due)
+	}
+}
@@ -6,12 +6,12 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"os"
 	"os/exec"
 	"runtime"
-	"strconv"
 	"strings"
 	"time"
+
+	"github.com/eiannone/keyboard"
 )
 
 // cleanSystemFull performs a full OS cleanup by executing a series of tasks
@@ -111,297 +111,335 @@ func cleanSystemFull() {
 	printSuccess(fmt.Sprintf("Cleanup finished. Cleaned: %.2f MB", freedMB))
 
 	// Trigger system notification / beep alert
-	go notifyAlarm()
+	go notifyAlarm(notification{Tool: "cleanup", Title: "Cleanup finished", Body: fmt.Sprintf("Cleaned: %.2f MB", freedMB), Urgency: urgencyLow})
 	pause() // wait for user to acknowledge
 }
 
-func CrunchySystemMonitor() {
-	// Channel to stop the monitor when user presses Enter
-	stop := make(chan struct{})
-
-	// Listen for Enter key in a separate goroutine
-	go func() {
-		bufio.NewScanner(os.Stdin).Scan() // waits until Enter
-		close(stop)                       // signal stop
-	}()
+// countdown is a deadline that can be paused and moved.
+// The remaining time is computed from the deadline on every call,
+// so a stalled process does not make it run late. The monotonic
+// clock stops while the machine sleeps, so the wall clock is checked
+// as well and the earlier of both wins: after a resume an overdue
+// countdown ends right away.
+type countdown struct {
+	total  time.Duration
+	end    time.Time     // deadline, carries the monotonic reading
+	wall   time.Time     // same deadline, wall clock only
+	paused time.Duration // time left while paused, 0 = running
+}
 
-	// Helper function to create a visual bar for percentages
-	createBar := func(value string) string {
-		value = strings.TrimSuffix(value, "%") // remove % sign
-		percent, err := strconv.Atoi(value)    // parse number
-		if err != nil {
-			return "[??????????]" // fallback if parsing fails
-		}
-		totalBars := 10
-		filled := percent * totalBars / 100 // scale to 10 bars
-		if filled > totalBars {
-			filled = totalBars
-		}
-		return "[" + strings.Repeat("█", filled) + strings.Repeat("░", totalBars-filled) + "]" // bar string
-	}
+// newCountdown starts a countdown over d
+func newCountdown(d time.Duration) *countdown {
+	c := &countdown{total: d}
+	c.start(d)
+	return c
+}
 
-	ticker := time.NewTicker(1 * time.Second) // refresh every second
-	defer ticker.Stop()
+// start sets the deadline d from now
+func (c *countdown) start(d time.Duration) {
+	now := time.Now()
+	c.end = now.Add(d)
+	c.wall = now.Round(0).Add(d) // Round(0) strips the monotonic reading
+	c.paused = 0
+}
 
-	for {
-		select {
-		case <-stop:
-			printInfo("System Monitor stopped") // user pressed Enter
-			return
+// Remaining returns the time left, negative once overdue
+func (c *countdown) Remaining() time.Duration {
+	if c.paused != 0 {
+		return c.paused
+	}
+	return min(time.Until(c.end), c.wall.Sub(time.Now().Round(0)))
+}
 
-		case <-ticker.C:
-			// Fetch fresh system data
-			cpuCores := GetCPUCores()                      // number of cores
-			cpuUsage := getCPUUsagePercent()               // current CPU %
-			topCPU := getTopCPUProcesses()                 // top 5 CPU-consuming processes
-			ramTotal := GetTotalRAM()                      // total RAM in MB
-			freeRam := getRAMUsagePercent()                // RAM usage %
-			diskName, diskTotal, diskUsed := GetDiskInfo() // disk info
-
-			clearScreen() // refresh terminal
-			printCommandTitle("CrunchyUtils")
-			printCommandTitle("System Monitor")
-			line()
-
-			// CPU info
-			fmt.Printf("%s# CPU Info:%s\n", YELLOW, RC)
-			fmt.Printf("└┬CPU Cores: %s\n", cpuCores)
-			fmt.Printf(" └Usage    : %s %s\n", cpuUsage, createBar(cpuUsage))
-
-			// Top CPU processes
-			fmt.Printf(" %s# Top CPU Tasks:%s\n", YELLOW, RC)
-			for i, task := range topCPU {
-				prefix := "├"
-				if i == len(topCPU)-1 { // last item gets └
-					prefix = "└"
-				}
-				fmt.Printf(" %s%s\n", prefix, task)
-			}
-			line()
-
-			// RAM info
-			fmt.Printf("%s# RAM Info:%s\n", YELLOW, RC)
-			fmt.Printf("└┬Total RAM: %s\n", ramTotal)
-			fmt.Printf(" └Usage    : %s %s\n", freeRam, createBar(freeRam))
-			line()
-
-			// Disk info: calculate used % if needed
-			totalGB, err1 := strconv.ParseFloat(strings.TrimSuffix(diskTotal, " GB"), 64)
-			usedGB, err2 := strconv.ParseFloat(strings.TrimSuffix(diskUsed, " GB"), 64)
-			if err1 == nil && err2 == nil && totalGB > 0 {
-				diskUsed = fmt.Sprintf("%d%%", int(usedGB*100/totalGB)) // calculate % usage
-			}
+// Target returns the wall-clock time the countdown ends at
+func (c *countdown) Target() time.Time {
+	if c.paused != 0 {
+		return time.Now().Add(c.paused)
+	}
+	return c.wall
+}
 
-			fmt.Printf("%s# Disk Info:%s\n", YELLOW, RC)
-			fmt.Printf("└┬Disk Name : %s\n", diskName)
-			fmt.Printf(" ├Total     : %s\n", diskTotal)
-			fmt.Printf(" └Used      : %s %s\n", diskUsed, createBar(diskUsed))
-			line()
+// Paused reports whether the countdown is paused
+func (c *countdown) Paused() bool {
+	return c.paused != 0
+}
 
-			fmt.Printf("Press [Enter] to exit\n")
-		}
+// TogglePause pauses or resumes, keeping the time left
+func (c *countdown) TogglePause() {
+	if c.paused != 0 {
+		c.start(c.paused)
+		return
 	}
+	c.paused = max(c.Remaining(), time.Nanosecond) // 0 would mean running
 }
 
-// countdownTimer runs a timer counting down from totalSeconds
-func countdownTimer(totalSeconds int) {
-	printInfo("Timer started. Press [Enter] to cancel\n")
+// Add moves the deadline. Taking off as much as is left or more is
+// ignored, a stray [-] must not end a shutdown timer on the spot.
+func (c *countdown) Add(d time.Duration) {
+	left := c.Remaining()
+	if c.paused != 0 {
+		left = c.paused
+	}
+	if left+d <= 0 {
+		return
+	}
+	if c.paused != 0 {
+		c.paused = left + d
+		return
+	}
+	c.start(left + d)
+}
 
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
+// Restart starts over with the original duration
+func (c *countdown) Restart() {
+	c.start(c.total)
+}
 
-	stop := make(chan struct{})
+// countdownLate is how far past the deadline counts as overdue,
+// e.g. after a suspend, rather than a normal tick
+const countdownLate = 2 * time.Second
 
-	// Listen for Enter key to stop the timer
-	go func() {
-		bufio.NewScanner(os.Stdin).Scan()
-		close(stop)
-	}()
+// countdownTimer counts down from totalSeconds and notifies when done
+func countdownTimer(totalSeconds int) {
+	done, late := runCountdown("Timer", totalSeconds)
+	if !done {
+		printInfo("Cancelled timer ")
+		return
+	}
+	printLate(late)
+	printSuccess("Timer finished")
+	go notifyAlarm(notification{Tool: "timer", Title: "Timer finished", Body: "Timer finished"})
+}
 
-	for totalSeconds >= 0 {
-		select {
-		case <-ticker.C:
-			// Display remaining time
-			fmt.Printf("\r%s%s%s", GREEN, formatTime(totalSeconds), RC)
-			totalSeconds--
-		case <-stop:
-			printInfo("Cancelled timer ")
-			return
-		}
+// printLate tells the user a countdown ended late (overdue after a suspend)
+func printLate(late time.Duration) {
+	if late >= countdownLate {
+		printInfo(fmt.Sprintf("Was due %s ago (system asleep?), finishing now", late.Round(time.Second)))
 	}
+}
 
-	printSuccess("Timer finished")
-	go notifyAlarm()
+// runCountdown shows a full-screen countdown titled title.
+// done is false if it was cancelled, late is how long past the
+// deadline it noticed the end (after a suspend).
+// Keys: P/Space pause/resume, +/- add or remove a minute,
+// R restart with the original duration, F big digits, Enter/Esc/Q cancel.
+func runCountdown(title string, totalSeconds int) (bool, time.Duration) {
+	return runCountdownWith(title, totalSeconds, nil)
 }
 
-// stopwatch runs a simple stopwatch
-func stopwatch() {
-	start := time.Now()
-	ticker := time.NewTicker(500 * time.Millisecond) // update every half second
-	defer ticker.Stop()
+// runCountdownWith is runCountdown with a notice line under the time.
+// notice is called every tick with the time left, "" shows nothing.
+func runCountdownWith(title string, totalSeconds int, notice func(left time.Duration) string) (bool, time.Duration) {
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		printError(fmt.Sprintf("Keyboard unavailable: %v", err))
+		return false, 0
+	}
+	defer keyboard.Close()
 
-	stop := make(chan struct{})
+	scr := newScreen()
+	defer scr.Close()
 
-	// Start listener for Enter key to stop the stopwatch
-	printInfo("Stopwatch started. Press [Enter] to stop\n")
-	go func() {
-		bufio.NewScanner(os.Stdin).Scan()
-		close(stop)
-	}()
+	// Frequent ticks keep the display on time, the screen
+	// only rewrites lines that changed
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	cd := newCountdown(time.Duration(totalSeconds) * time.Second)
+	big := config.Display.BigDigits // [F] toggles it for this screen only
 
 	for {
+		left := cd.Remaining()
+		if left <= 0 {
+			return true, -left
+		}
+
+		// Display remaining time, rounded up so 00:00:00 means done
+		color, state := countdownColor(left, cd.total), ""
+		if cd.Paused() {
+			color, state = YELLOW, "PAUSED"
+		}
+		secs := int((left + time.Second - 1) / time.Second)
+		target := "ends at " + cd.Target().Format("15:04:05")
+		keyHelp := "[P] pause/resume [+/-] 1 minute [R] restart [F] big digits [Enter] cancel"
+		note := ""
+		if notice != nil {
+			note = notice(left)
+		}
+
+		if rows := bigText(formatTime(secs)); big && bigFits(rows) {
+			frac := 1 - left.Seconds()/cd.total.Seconds()
+			fprintBigFrame(scr, title, bigClock(rows, color, frac, target, YELLOW+state+RC, RED+note+RC), keyHelp)
+		} else {
+			fprintCommandTitle(scr, title)
+			fline(scr)
+			fmt.Fprintf(scr, "\n   %s%s%s  %s  %s\n", color, formatTime(secs), RC, target, state)
+			fmt.Fprintf(scr, "   %s%s%s\n", RED, note, RC)
+			fline(scr)
+			fmt.Fprintf(scr, "%s\n", keyHelp)
+		}
+		scr.Flush()
+
 		select {
 		case <-ticker.C:
-			// Calculate elapsed time and display
-			elapsed := int(time.Since(start).Seconds())
-			fmt.Printf("\r%s%s%s", GREEN, formatTime(elapsed), RC)
-		case <-stop:
-			printInfo("Stopwatch stopped")
-			return
+		case <-termResized:
+		case ev := <-keys:
+			switch {
+			case isStopKey(ev):
+				return false, 0
+			case ev.Rune == 'p', ev.Rune == 'P', ev.Key == keyboard.KeySpace:
+				cd.TogglePause()
+			case ev.Rune == '+', ev.Rune == '=':
+				cd.Add(time.Minute)
+			case ev.Rune == '-', ev.Rune == '_':
+				cd.Add(-time.Minute)
+			case ev.Rune == 'r', ev.Rune == 'R':
+				cd.Restart()
+			case ev.Rune == 'f', ev.Rune == 'F':
+				big = !big
+			}
 		}
 	}
 }
 
 // clipboardLogger continuously logs clipboard changes
 func clipboardLogger() {
-	var prev string
-	stop := make(chan struct{})
-
-	// Goroutine listens for Enter key to stop the logger
-	go func() {
-		printInfo("Clipboard logger started. Press [Enter] to cancel\n")
-		bufio.NewScanner(os.Stdin).Scan()
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		printError(fmt.Sprintf("Keyboard unavailable: %v", err))
+		return
+	}
+	defer keyboard.Close()
+
+	scr := newScreen()
+	defer scr.Close()
+
+	var (
+		prev    string
+		entries []string // everything logged this session
+	)
+
+	// The screen only holds the session while it runs,
+	// so print the full log once we are back
+	defer func() {
+		scr.Close()
+		for _, e := range entries {
+			fmt.Println(e)
+		}
 		printInfo("Cancelled clipboard logger")
-		close(stop)
 	}()
 
+	ticker := time.NewTicker(time.Second) // Poll clipboard every second
+	defer ticker.Stop()
+
 	for {
+		clip, err := readClipboard()
+
+		// Handle errors reading clipboard
+		if err != nil {
+			scr.Close()
+			printError("Clipboard read failed: ")
+			fmt.Printf("%s", err)
+			return
+		}
+
+		// Log new clipboard content if it has changed
+		if clip != "" && clip != prev {
+			prev = clip
+			timestamp := time.Now().Format("2006-01-02 15:04:05")
+			entries = append(entries, fmt.Sprintf("%s%s Copied:%s \"%s\"", YELLOW, timestamp, RC, clip))
+		}
+
+		fprintCommandTitle(scr, "Clipboard Logger")
+		fline(scr)
+		// Newest entries that fit between header and footer
+		_, rows := termSize()
+		shown := entries[max(0, len(entries)-(rows-5)):]
+		for _, e := range shown {
+			fmt.Fprintln(scr, e)
+		}
+		fline(scr)
+		fmt.Fprintf(scr, "Press [Enter] to cancel\n")
+		scr.Flush()
+
 		select {
-		case <-stop:
-			return // Exit main loop cleanly
-		default:
-			// Read current clipboard content depending on OS
-			clip, err := func() (string, error) {
-				switch runtime.GOOS {
-				case "windows":
-					// Windows: use PowerShell Get-Clipboard
-					out, err := exec.Command("powershell", "-command", "Get-Clipboard").Output()
-					return strings.TrimSpace(string(out)), err
-				default:
-					// Linux/Unix: try xclip or xsel
-					var clip string
-					var toolFound bool
-
-					for _, tool := range []string{"xclip", "xsel"} {
-						path, err := exec.LookPath(tool)
-						if err != nil {
-							continue
-						}
-						toolFound = true
-						var cmd *exec.Cmd
-						if tool == "xclip" {
-							cmd = exec.Command(path, "-o", "-selection", "clipboard")
-						} else {
-							cmd = exec.Command(path, "--clipboard", "--output")
-						}
-						out, err := cmd.Output()
-						if err == nil {
-							clip = strings.TrimSpace(string(out))
-							break
-						}
-					}
-
-					if !toolFound {
-						return "", fmt.Errorf("no clipboard tool found (xclip/xsel)\n")
-					}
-					return clip, nil
-				}
-			}()
+		case <-ticker.C:
+		case ev := <-keys:
+			if isStopKey(ev) {
+				return // Exit main loop cleanly
+			}
+		}
+	}
+}
+
+// readClipboard returns the current clipboard text
+func readClipboard() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		// Windows: use PowerShell Get-Clipboard
+		out, err := exec.Command("powershell", "-command", "Get-Clipboard").Output()
+		return strings.TrimSpace(string(out)), err
+	default:
+		// Linux/Unix: try xclip or xsel
+		var clip string
+		var toolFound bool
 
-			// Handle errors reading clipboard
+		for _, tool := range []string{"xclip", "xsel"} {
+			path, err := exec.LookPath(tool)
 			if err != nil {
-				printError("Clipboard read failed: ")
-				fmt.Printf("%s", err)
-				return
+				continue
 			}
-
-			// Print new clipboard content if it has changed
-			if clip != "" && clip != prev {
-				prev = clip
-				timestamp := time.Now().Format("2006-01-02 15:04:05")
-				fmt.Printf("%s%s Copied:%s \"%s\"\n", YELLOW, timestamp, RC, clip)
+			toolFound = true
+			var cmd *exec.Cmd
+			if tool == "xclip" {
+				cmd = exec.Command(path, "-o", "-selection", "clipboard")
+			} else {
+				cmd = exec.Command(path, "--clipboard", "--output")
+			}
+			out, err := cmd.Output()
+			if err == nil {
+				clip = strings.TrimSpace(string(out))
+				break
 			}
+		}
 
-			time.Sleep(time.Second) // Poll clipboard every second
+		if !toolFound {
+			return "", fmt.Errorf("no clipboard tool found (xclip/xsel)\n")
 		}
+		return clip, nil
 	}
 }
 
 // powerTimer starts a shutdown or reboot timer and executes the action
 func powerTimer(action, toption string) {
 	// Ask user for timer duration
-	fmt.Printf("Enter time for %s timer (HH:MM:SS)%s", action, PROMPT)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input)
-
-	secs, err := parseTimeInput(input)
-	if err != nil {
-		fmt.Printf("Invalid time format\n")
-		pause()
+	secs, _, ok := askTimerTime("Enter time for " + action + " timer")
+	if !ok {
 		return
 	}
 
-	printInfo("Timer started. Press [Enter] to cancel\n")
-
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
-
-	cancel := make(chan struct{})
-
-	// Goroutine listens for Enter key to cancel
-	go func() {
-		bufio.NewScanner(os.Stdin).Scan()
-		close(cancel)
-	}()
-
-	// Countdown loop
-	for secs > 0 {
-		select {
-		case <-ticker.C:
-			fmt.Printf("\r%sTime left: %s%s", GREEN, formatTime(secs), RC)
-			secs--
-		case <-cancel:
-			printInfo("Cancelled shutdown/reboot timer")
-			return
-		}
+	a := shutdownAction
+	if toption == "Wreboot" || toption == "Lreboot" {
+		a = rebootAction
 	}
 
-	printSuccess("Finished timer. Executing...\n")
-	notifyAlarm()
-	time.Sleep(2 * time.Second)
-
-	// Determine the shutdown/reboot command based on OS
-	var cmd []string
-	switch goos {
-	case "windows":
-		switch toption {
-		case "Wshutdown":
-			cmd = []string{"shutdown", "/s", "/f", "/t", "0"}
-		case "Wreboot":
-			cmd = []string{"shutdown", "/r", "/f", "/t", "0"}
-		}
-	default: // linux/unix
-		switch toption {
-		case "Lshutdown":
-			cmd = []string{"shutdown", "-h", "now"}
-		case "Lreboot":
-			cmd = []string{"shutdown", "-r", "now"}
-		}
+	// Same deadline-based countdown as the timer, warning
+	// everyone logged in as the configured marks pass
+	w := newPowerWarnings(a, time.Duration(secs)*time.Second)
+	done, late := runCountdownWith("Shutdown/Reboot Timer", secs, w.Notice)
+	if done {
+		printLate(late)
+		done = powerGrace(a, w)
 	}
-
-	if len(cmd) > 0 {
-		runCommand(cmd) // using the centralized helper
+	if !done {
+		w.Cancelled()
+		printInfo("Cancelled shutdown/reboot timer")
+		return
 	}
+
+	printSuccess("Finished timer. Executing...\n")
+	notifyAlarm(notification{Tool: "power", Title: "Power timer", Body: "Timer finished, executing " + action, Urgency: urgencyCritical})
+	runPowerAction(a)
 }
 
 // weather fetches and prints weather info for a city
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountdownAdd(t *testing.T) {
+	c := newCountdown(90 * time.Second)
+	c.Add(-time.Minute)
+	if left := c.Remaining(); left <= 25*time.Second || left > 30*time.Second {
+		t.Errorf("90s - 1m: got %s left, want about 30s", left)
+	}
+
+	// Would end it: ignored
+	c.Add(-time.Minute)
+	if left := c.Remaining(); left <= 25*time.Second {
+		t.Errorf("30s - 1m: got %s left, want the key ignored", left)
+	}
+
+	c.Add(time.Minute)
+	if left := c.Remaining(); left <= 85*time.Second {
+		t.Errorf("30s + 1m: got %s left, want about 90s", left)
+	}
+
+	// Same while paused
+	c.TogglePause()
+	c.Add(-2 * time.Minute)
+	c.Add(-time.Minute)
+	if !c.Paused() || c.Remaining() <= 25*time.Second {
+		t.Errorf("paused: got %s left (paused %v), want about 30s", c.Remaining(), c.Paused())
+	}
+}